@@ -0,0 +1,144 @@
+package log
+
+import (
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//文件路径的输出方式
+const (
+	LOG_CALLER_SHORT    = iota //文件名 handler.go
+	LOG_CALLER_RELATIVE        //相对模块根目录的路径 api/handler.go
+	LOG_CALLER_FULL            //完整路径 /home/user/project/api/handler.go
+)
+
+//调用位置, 按 pc 缓存
+type callerFrame struct {
+	path     string //完整路径
+	relative string //相对模块根目录的路径
+	file     string //文件名
+	line     int
+	function string //完整函数名 github.com/user/project/api.(*Server).Handle
+	pkg      string //包路径 github.com/user/project/api
+	funcName string //包名.函数名 api.(*Server).Handle
+}
+
+func (my *callerFrame) File(mode int) string {
+	switch mode {
+	case LOG_CALLER_RELATIVE:
+		return my.relative
+	case LOG_CALLER_FULL:
+		return my.path
+	default:
+		return my.file
+	}
+}
+
+var unknownFrame = &callerFrame{path: "???", relative: "???", file: "???", function: "???", pkg: "???", funcName: "???"}
+
+var callerFrames sync.Map
+
+//skip 为0时返回调用 callerPC 的函数的返回地址
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) < 1 {
+		return 0
+	}
+	return pcs[0]
+}
+
+var helpers sync.Map
+var helperCount int32
+
+//将调用 Helper 的函数标记为辅助函数, 输出调用位置时跳过该函数(类似 testing.T.Helper)
+func Helper() {
+	function := frameOf(callerPC(1)).function
+	if _, loaded := helpers.LoadOrStore(function, struct{}{}); !loaded {
+		atomic.AddInt32(&helperCount, 1)
+	}
+}
+
+//同 callerPC, 并跳过标记为辅助函数的调用
+func callerHelperPC(skip int) uintptr {
+	if atomic.LoadInt32(&helperCount) == 0 {
+		return callerPC(skip + 1)
+	}
+	var pcs [32]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	for i := 0; i < n; i++ {
+		if _, ok := helpers.Load(frameOf(pcs[i]).function); !ok {
+			return pcs[i]
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return pcs[n-1]
+}
+
+func frameOf(pc uintptr) *callerFrame {
+	if pc == 0 {
+		return unknownFrame
+	}
+	if frame, ok := callerFrames.Load(pc); ok {
+		return frame.(*callerFrame)
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return unknownFrame
+	}
+	out := &callerFrame{path: frame.File, file: shortFile(frame.File), line: frame.Line, function: frame.Function}
+	out.pkg, out.funcName = splitFunction(frame.Function)
+	out.relative = relativePath(out.pkg, frame.File)
+	callerFrames.Store(pc, out)
+	return out
+}
+
+//github.com/user/project/api.(*Server).Handle => github.com/user/project/api, api.(*Server).Handle
+func splitFunction(function string) (pkg string, funcName string) {
+	lastSlash := strings.LastIndexByte(function, '/')
+	dot := strings.IndexByte(function[lastSlash+1:], '.')
+	if dot == -1 {
+		return function, function[lastSlash+1:]
+	}
+	return function[:lastSlash+1+dot], function[lastSlash+1:]
+}
+
+var modulePaths []string
+var modulePathsOnce sync.Once
+
+//包路径去掉所属模块路径后拼接文件名; main 包使用相对工作目录的路径
+func relativePath(pkg string, path string) string {
+	file := shortFile(path)
+	if pkg == "main" {
+		if rel, err := filepath.Rel(execDir(), path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+		return file
+	}
+	modulePathsOnce.Do(func() {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			modulePaths = append(modulePaths, info.Main.Path)
+			for _, dep := range info.Deps {
+				modulePaths = append(modulePaths, dep.Path)
+			}
+		}
+	})
+	module := ""
+	for _, modulePath := range modulePaths {
+		if len(modulePath) > len(module) && (pkg == modulePath || strings.HasPrefix(pkg, modulePath+"/")) {
+			module = modulePath
+		}
+	}
+	if module == "" {
+		return pkg + "/" + file
+	}
+	if pkg == module {
+		return file
+	}
+	return pkg[len(module)+1:] + "/" + file
+}
@@ -0,0 +1,40 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func skipWrapper(logger *stdLogger, msg string) {
+	logger.Info(msg)
+}
+
+func helperWrapper(logger *stdLogger, msg string) {
+	Helper()
+	logger.Info(msg)
+}
+
+func nestedHelperWrapper(logger *stdLogger, msg string) {
+	Helper()
+	helperWrapper(logger, msg)
+}
+
+//封装函数中输出的调用位置为封装函数的调用者
+func TestCallerSkipAndHelper(t *testing.T) {
+	out := &syncBuffer{}
+	logger := NewLogger(LOG_LEVEL_DEBUG, false, NewLogStdWriter(out))
+	if err := logger.SetLayout("%S:%l %m"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, line, _ := runtime.Caller(0)
+	skipWrapper(logger.WithCallerSkip(1), "skip")
+	helperWrapper(logger, "helper")
+	nestedHelperWrapper(logger, "nested")
+	logger.Info("direct")
+	logger.Wait()
+	want := fmt.Sprintf("caller_test.go:%d skip\ncaller_test.go:%d helper\ncaller_test.go:%d nested\ncaller_test.go:%d direct\n", line+1, line+2, line+3, line+4)
+	if got := out.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+//时钟, 用于替换日志时间及文件保留策略中的当前时间
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	sec, nsec := now()
+	return time.Unix(sec, int64(nsec))
+}
+
+var defaultClock Clock = systemClock{}
+
+//手动推进的时钟, 用于测试文件切换时间点等
+type ManualClock struct {
+	t  time.Time
+	mu sync.Mutex
+}
+
+func NewManualClock(t time.Time) *ManualClock {
+	return &ManualClock{t: t}
+}
+
+func (my *ManualClock) Now() time.Time {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	return my.t
+}
+
+func (my *ManualClock) Set(t time.Time) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.t = t
+}
+
+func (my *ManualClock) Add(d time.Duration) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.t = my.t.Add(d)
+}
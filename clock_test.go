@@ -0,0 +1,67 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+//日志时间取自 logger 的时钟, 按小时及按天的文件恰好在边界切换
+func TestManualClockSwitchFiles(t *testing.T) {
+	dir := t.TempDir()
+	hourly := NewLogFileWriter(filepath.Join(dir, "hour.log.%Y-%m-%d-%H"))
+	daily := NewLogFileWriter(filepath.Join(dir, "day.log.%Y-%m-%d"))
+	clock := NewManualClock(time.Date(2024, 1, 1, 22, 59, 59, 999999999, time.UTC))
+	logger := NewLogger(LOG_LEVEL_DEBUG, false, hourly, daily)
+	logger.SetLocation(time.UTC)
+	logger.SetClock(clock)
+	hourly.SetClock(clock)
+	daily.SetClock(clock)
+
+	logger.Info("a")
+	clock.Add(time.Nanosecond) //23:00
+	logger.Info("b")
+	clock.Set(time.Date(2024, 1, 1, 23, 59, 59, 999999999, time.UTC))
+	logger.Info("c")
+	clock.Add(time.Nanosecond) //次日 00:00
+	logger.Info("d")
+	logger.Wait()
+
+	expects := map[string][]string{
+		"hour.log.2024-01-01-22": {"[INFO 2024/01/01 22:59:59 ", "a"},
+		"hour.log.2024-01-01-23": {"[INFO 2024/01/01 23:00:00 ", "b", "[INFO 2024/01/01 23:59:59 ", "c"},
+		"hour.log.2024-01-02-00": {"[INFO 2024/01/02 00:00:00 ", "d"},
+		"day.log.2024-01-01":     {"[INFO 2024/01/01 22:59:59 ", "a", "[INFO 2024/01/01 23:00:00 ", "b", "[INFO 2024/01/01 23:59:59 ", "c"},
+		"day.log.2024-01-02":     {"[INFO 2024/01/02 00:00:00 ", "d"},
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(expects) {
+		t.Fatalf("got %d files, want %d", len(entries), len(expects))
+	}
+	for name, expect := range expects {
+		lines := readLines(t, filepath.Join(dir, name))
+		if len(lines)*2 != len(expect) {
+			t.Fatalf("%s: got lines %q", name, lines)
+		}
+		for i, line := range lines {
+			prefix, content := expect[i*2], expect[i*2+1]
+			if !strings.HasPrefix(line, prefix) || !strings.HasSuffix(line, "] "+content) {
+				t.Errorf("%s line %d = %q, want prefix %q and content %q", name, i, line, prefix, content)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	traceIDKey
+	spanIDKey
+)
+
+//返回带请求ID的 context, 日志中输出为 request_id
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+//返回带链路追踪ID的 context, 日志中输出为 trace_id, span_id; spanID 为空时不输出
+func WithTraceID(ctx context.Context, traceID string, spanID string) context.Context {
+	ctx = context.WithValue(ctx, traceIDKey, traceID)
+	if spanID != "" {
+		ctx = context.WithValue(ctx, spanIDKey, spanID)
+	}
+	return ctx
+}
+
+//WithRequestID 设置的请求ID
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+//WithTraceID 设置的链路追踪ID
+func TraceID(ctx context.Context) (traceID string, spanID string) {
+	traceID, _ = ctx.Value(traceIDKey).(string)
+	spanID, _ = ctx.Value(spanIDKey).(string)
+	return
+}
+
+func defaultContextExtractor(ctx context.Context) []Field {
+	var fields []Field
+	if traceID, spanID := TraceID(ctx); traceID != "" {
+		fields = append(fields, Field{Key: "trace_id", Value: traceID})
+		if spanID != "" {
+			fields = append(fields, Field{Key: "span_id", Value: spanID})
+		}
+	}
+	if requestID := RequestID(ctx); requestID != "" {
+		fields = append(fields, Field{Key: "request_id", Value: requestID})
+	}
+	return fields
+}
+
+var contextExtractors atomic.Value //[]func(ctx context.Context) []Field
+var contextExtractorsMu sync.Mutex
+
+func init() {
+	contextExtractors.Store([]func(ctx context.Context) []Field{defaultContextExtractor})
+}
+
+//注册从 context 中提取字段的函数, 如 OpenTelemetry 的 span; 按注册顺序在 XxxCtx 日志中输出
+func RegisterContextExtractor(extractor func(ctx context.Context) []Field) {
+	if extractor == nil {
+		return
+	}
+	contextExtractorsMu.Lock()
+	defer contextExtractorsMu.Unlock()
+	old := contextExtractors.Load().([]func(ctx context.Context) []Field)
+	extractors := make([]func(ctx context.Context) []Field, 0, len(old)+1)
+	contextExtractors.Store(append(append(extractors, old...), extractor))
+}
+
+//context 中提取的字段在前, fields 在后
+func withContextFields(ctx context.Context, fields []Field) []Field {
+	if ctx == nil {
+		return fields
+	}
+	var out []Field
+	for _, extractor := range contextExtractors.Load().([]func(ctx context.Context) []Field) {
+		out = append(out, extractor(ctx)...)
+	}
+	if len(out) == 0 {
+		return fields
+	}
+	return append(out, fields...)
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"strconv"
 	"time"
 	_ "unsafe"
 )
@@ -16,16 +17,25 @@ const fourYears = 365 + 365 + 366 + 365 //每个四年的总天数
 
 var norMonth = [12]int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}  //平年
 var leapMonth = [12]int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31} //闰年
-var offset int64
 
-//go:linkname now time.now
-func now() (sec int64, nsec int32)
+var weekdayNames = [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+var monthNames = [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
 
-func unix() int64 {
-	sec, _ := now()
-	return sec
+//组合格式符
+var compositeVerbs = map[byte]string{
+	'F': "%Y-%m-%d",
+	'T': "%H:%M:%S",
+	'D': "%m/%d/%y",
+	'R': "%H:%M",
+	'r': "%I:%M:%S %p",
+	'c': "%a %b %e %H:%M:%S %Y",
+	'x': "%m/%d/%y",
+	'X': "%H:%M:%S",
 }
 
+//go:linkname now time.now
+func now() (sec int64, nsec int32)
+
 func dateClock(unixSecLocal int64) (year, month, day, hour, min, sec, yDay, daySecond int) {
 	var nRemain int
 	if unixSecLocal < 0 {
@@ -92,7 +102,13 @@ type DateTime interface {
 	Hour()int
 	Min()int
 	Sec()int
+	MilliSec()int //毫秒(0-999)
+	MicroSec()int //微秒(0-999999)
+	NanoSec()int //纳秒(0-999999999)
+	Unix()int64
+	Zone() (name string, offset int)
 	YmdHMS() string
+	Format(formatter string) string
 }
 
 type datetime struct {
@@ -103,6 +119,12 @@ type datetime struct {
 	hour  int
 	min   int
 	sec   int
+	nsec  int
+	yday  int //年内的第几天（1-366）
+	wday  int //星期（0-6, 0为星期日）
+	loc    *time.Location //nil 为本地时区
+	zone   string
+	offset int64 //相对 UTC 的偏移秒数
 }
 
 func (my *datetime) Year()int{
@@ -129,21 +151,110 @@ func (my *datetime) Sec()int{
 	return my.sec
 }
 
+func (my *datetime) MilliSec()int{
+	return my.nsec / 1000000
+}
+
+func (my *datetime) MicroSec()int{
+	return my.nsec / 1000
+}
+
+func (my *datetime) NanoSec()int{
+	return my.nsec
+}
+
+func (my *datetime) Unix()int64{
+	return my.unix
+}
+
+func (my *datetime) Zone() (name string, offset int) {
+	return my.zone, int(my.offset)
+}
+
+//设置时区, nil 为本地时区; 每秒按时区规则重新计算偏移, 夏令时切换即时生效
+func (my *datetime) SetLocation(loc *time.Location) {
+	my.loc = loc
+	my.year = 0
+}
+
+func (my *datetime) location() *time.Location {
+	if my.loc == nil {
+		return time.Local
+	}
+	return my.loc
+}
+
 func (my *datetime) YmdHMS() string {
 	return my.format("%Y/%m/%d %H:%M:%S")
 }
 
-func (my *datetime) flushTo(unix int64) {
-	if unix == my.unix {
+func (my *datetime) Format(formatter string) string {
+	return my.format(formatter)
+}
+
+func (my *datetime) flushTo(unix int64, nsec int32) {
+	my.nsec = int(nsec)
+	if unix == my.unix && my.year != 0 {
 		return
 	}
-	my.year, my.month, my.day, my.hour, my.min, my.sec, _, _ = dateClock(unix + offset)
+	my.unix = unix
+	var offset int
+	my.zone, offset = time.Unix(unix, 0).In(my.location()).Zone()
+	my.offset = int64(offset)
+	my.year, my.month, my.day, my.hour, my.min, my.sec, my.yday, _ = dateClock(unix + my.offset)
+	my.wday = weekday(unix + my.offset)
+}
+
+//1970-01-01 为星期四
+func weekday(unixSecLocal int64) int {
+	days := unixSecLocal / daySec
+	if unixSecLocal < 0 && unixSecLocal%daySec != 0 {
+		days--
+	}
+	wday := int((days + 4) % 7)
+	if wday < 0 {
+		wday += 7
+	}
+	return wday
+}
+
+//ISO 8601 年份中的周数(52或53)
+func isoWeeksInYear(year int) int {
+	p := func(y int) int { return (y + y/4 - y/100 + y/400) % 7 }
+	if p(year) == 4 || p(year-1) == 3 {
+		return 53
+	}
+	return 52
+}
+
+//ISO 8601 周数年份及周数, 周一为一周的第一天
+func (my *datetime) isoWeek() (year, week int) {
+	wday := my.wday
+	if wday == 0 {
+		wday = 7
+	}
+	year = my.year
+	week = (my.yday - wday + 10) / 7
+	if week < 1 {
+		year--
+		week = isoWeeksInYear(year)
+	} else if week > isoWeeksInYear(year) {
+		year++
+		week = 1
+	}
+	return
 }
 
 func (my *datetime) format(formatter string) string {
 	//var theTime []byte
 	var theTime = getBuffer(len(formatter) * 2)
 	defer theTime.free()
+	my.appendFormat(theTime, formatter)
+	return string(*theTime)
+}
+
+//按 strftime 格式追加, 不支持的格式符原样输出其字符
+func (my *datetime) appendFormat(theTime *Buffer, formatter string) {
 	length := len(formatter)
 	for i := 0; i < length; {
 		c := formatter[i]
@@ -155,18 +266,86 @@ func (my *datetime) format(formatter string) string {
 			switch c2 {
 			case 'Y': //四位数的年份表示（0000-9999）
 				theTime.AppendInt(my.year, 4)
+			case 'y': //两位数的年份表示（00-99）
+				theTime.AppendInt(my.year%100, 2)
+			case 'C': //世纪（00-99）
+				theTime.AppendInt(my.year/100, 2)
 			case 'm': //月份（01-12）
 				theTime.AppendInt(my.month, 2)
+			case 'b', 'h': //简写的月份名称（Jan）
+				theTime.AppendString(monthNames[my.month-1][:3])
+			case 'B': //完整的月份名称（January）
+				theTime.AppendString(monthNames[my.month-1])
 			case 'd': //月内中的一天（0-31）
 				theTime.AppendInt(my.day, 2)
+			case 'e': //月内中的一天, 不足两位时前面补空格（ 1-31）
+				if my.day < 10 {
+					theTime.AppendBytes(' ')
+				}
+				theTime.AppendInt(my.day, 0)
+			case 'j': //年内的一天（001-366）
+				theTime.AppendInt(my.yday, 3)
+			case 'a': //简写的星期名称（Mon）
+				theTime.AppendString(weekdayNames[my.wday][:3])
+			case 'A': //完整的星期名称（Monday）
+				theTime.AppendString(weekdayNames[my.wday])
+			case 'u': //星期（1-7, 1为星期一）
+				if my.wday == 0 {
+					theTime.AppendInt(7, 0)
+				} else {
+					theTime.AppendInt(my.wday, 0)
+				}
+			case 'w': //星期（0-6, 0为星期日）
+				theTime.AppendInt(my.wday, 0)
+			case 'V': //ISO 8601 周数（01-53）
+				_, week := my.isoWeek()
+				theTime.AppendInt(week, 2)
+			case 'G': //ISO 8601 周数对应的年份
+				year, _ := my.isoWeek()
+				theTime.AppendInt(year, 4)
+			case 'g': //ISO 8601 周数对应的两位数年份
+				year, _ := my.isoWeek()
+				theTime.AppendInt(year%100, 2)
 			case 'H': //24小时制小时数（0-23）
 				theTime.AppendInt(my.hour, 2)
+			case 'I': //12小时制小时数（01-12）
+				hour := my.hour % 12
+				if hour == 0 {
+					hour = 12
+				}
+				theTime.AppendInt(hour, 2)
+			case 'p': //AM 或 PM
+				if my.hour < 12 {
+					theTime.AppendString("AM")
+				} else {
+					theTime.AppendString("PM")
+				}
 			case 'M': //分钟数（00=59）
 				theTime.AppendInt(my.min, 2)
 			case 'S': //秒（00-59）
 				theTime.AppendInt(my.sec, 2)
+			case 'L': //毫秒（000-999）
+				theTime.AppendInt(my.MilliSec(), 3)
+			case 'f': //微秒（000000-999999）
+				theTime.AppendInt(my.MicroSec(), 6)
+			case 'N': //纳秒（000000000-999999999）
+				theTime.AppendInt(my.nsec, 9)
+			case 's': //Unix 时间戳（秒）
+				*theTime = strconv.AppendInt(*theTime, my.unix, 10)
+			case 'z': //时区偏移（+0800）
+				appendZoneOffset(theTime, my.offset, false)
+			case 'Z': //时区名称（CST）
+				theTime.AppendString(my.zone)
+			case 'n': //换行
+				theTime.AppendBytes('\n')
+			case 't': //制表符
+				theTime.AppendBytes('\t')
 			default:
-				theTime.AppendBytes(c2)
+				if composite, ok := compositeVerbs[c2]; ok {
+					my.appendFormat(theTime, composite)
+				} else {
+					theTime.AppendBytes(c2)
+				}
 			}
 			i += 2
 		} else {
@@ -174,10 +353,4 @@ func (my *datetime) format(formatter string) string {
 			i += 1
 		}
 	}
-	return string(*theTime)
 }
-
-func init()  {
-	_, local := time.Now().Zone()
-	offset = int64(local)
-}
\ No newline at end of file
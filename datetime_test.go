@@ -0,0 +1,118 @@
+package log
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+var formatVerbs = []struct {
+	verb   string
+	expect func(t time.Time) string
+}{
+	{"%Y", func(t time.Time) string { return t.Format("2006") }},
+	{"%y", func(t time.Time) string { return t.Format("06") }},
+	{"%C", func(t time.Time) string { return fmt.Sprintf("%02d", t.Year()/100) }},
+	{"%m", func(t time.Time) string { return t.Format("01") }},
+	{"%b", func(t time.Time) string { return t.Format("Jan") }},
+	{"%h", func(t time.Time) string { return t.Format("Jan") }},
+	{"%B", func(t time.Time) string { return t.Format("January") }},
+	{"%d", func(t time.Time) string { return t.Format("02") }},
+	{"%e", func(t time.Time) string { return t.Format("_2") }},
+	{"%j", func(t time.Time) string { return t.Format("002") }},
+	{"%a", func(t time.Time) string { return t.Format("Mon") }},
+	{"%A", func(t time.Time) string { return t.Format("Monday") }},
+	{"%u", func(t time.Time) string {
+		if t.Weekday() == time.Sunday {
+			return "7"
+		}
+		return fmt.Sprint(int(t.Weekday()))
+	}},
+	{"%w", func(t time.Time) string { return fmt.Sprint(int(t.Weekday())) }},
+	{"%V", func(t time.Time) string { _, week := t.ISOWeek(); return fmt.Sprintf("%02d", week) }},
+	{"%G", func(t time.Time) string { year, _ := t.ISOWeek(); return fmt.Sprintf("%04d", year) }},
+	{"%g", func(t time.Time) string { year, _ := t.ISOWeek(); return fmt.Sprintf("%02d", year%100) }},
+	{"%H", func(t time.Time) string { return t.Format("15") }},
+	{"%I", func(t time.Time) string { return t.Format("03") }},
+	{"%p", func(t time.Time) string { return t.Format("PM") }},
+	{"%M", func(t time.Time) string { return t.Format("04") }},
+	{"%S", func(t time.Time) string { return t.Format("05") }},
+	{"%L", func(t time.Time) string { return t.Format(".000")[1:] }},
+	{"%f", func(t time.Time) string { return t.Format(".000000")[1:] }},
+	{"%N", func(t time.Time) string { return t.Format(".000000000")[1:] }},
+	{"%s", func(t time.Time) string { return fmt.Sprint(t.Unix()) }},
+	{"%z", func(t time.Time) string { return t.Format("-0700") }},
+	{"%Z", func(t time.Time) string { return t.Format("MST") }},
+	{"%n", func(t time.Time) string { return "\n" }},
+	{"%t", func(t time.Time) string { return "\t" }},
+	{"%%", func(t time.Time) string { return "%" }},
+	{"%F", func(t time.Time) string { return t.Format("2006-01-02") }},
+	{"%T", func(t time.Time) string { return t.Format("15:04:05") }},
+	{"%D", func(t time.Time) string { return t.Format("01/02/06") }},
+	{"%R", func(t time.Time) string { return t.Format("15:04") }},
+	{"%r", func(t time.Time) string { return t.Format("03:04:05 PM") }},
+	{"%c", func(t time.Time) string { return t.Format("Mon Jan _2 15:04:05 2006") }},
+	{"%x", func(t time.Time) string { return t.Format("01/02/06") }},
+	{"%X", func(t time.Time) string { return t.Format("15:04:05") }},
+	{"[%Y/%m/%d %H:%M:%S.%L]", func(t time.Time) string { return t.Format("[2006/01/02 15:04:05.000]") }},
+}
+
+//包含 ISO 周数跨年, 闰日, 12小时制边界及夏令时切换
+var formatTimes = []string{
+	"1970-01-01T00:00:00Z",
+	"2004-12-31T23:59:59.999999999Z", //ISO 2004 第53周
+	"2005-01-02T12:00:00Z",           //周日, ISO 2004 第53周
+	"2008-12-29T00:00:00Z",           //周一, ISO 2009 第1周
+	"2010-01-03T00:00:00Z",           //ISO 2009 第53周
+	"2020-12-31T12:30:45.123456789Z", //ISO 2020 第53周
+	"2021-01-01T00:00:00Z",
+	"2024-02-29T23:00:00.5Z",
+	"2024-12-30T01:02:03Z", //ISO 2025 第1周
+	"2026-03-08T06:59:59Z", //纽约夏令时开始前
+	"2026-03-08T07:00:00Z", //纽约夏令时开始后
+	"2026-11-01T05:30:00Z", //纽约夏令时结束
+	"2026-12-31T11:59:59Z",
+	"2027-01-03T12:00:00Z",
+	"2037-07-04T00:00:00.000001Z",
+}
+
+func TestDatetimeFormat(t *testing.T) {
+	locations := []*time.Location{time.UTC, time.FixedZone("X", -(3*3600 + 30*60))}
+	for _, name := range []string{"Asia/Shanghai", "America/New_York", "Australia/Lord_Howe"} {
+		if loc, err := time.LoadLocation(name); err == nil {
+			locations = append(locations, loc)
+		}
+	}
+	for _, loc := range locations {
+		for _, value := range formatTimes {
+			utc, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tm := utc.In(loc)
+			dt := &datetime{}
+			dt.SetLocation(loc)
+			dt.flushTo(tm.Unix(), int32(tm.Nanosecond()))
+			for _, test := range formatVerbs {
+				if got, want := dt.Format(test.verb), test.expect(tm); got != want {
+					t.Errorf("%s in %s: format(%q) = %q, want %q", value, loc, test.verb, got, want)
+				}
+			}
+		}
+	}
+}
+
+//同一秒内只更新纳秒, 跨秒时重新计算
+func TestDatetimeFlushTo(t *testing.T) {
+	dt := &datetime{}
+	dt.SetLocation(time.UTC)
+	dt.flushTo(86399, 1)
+	dt.flushTo(86399, 999000000)
+	if got := dt.Format("%F %T.%L"); got != "1970-01-01 23:59:59.999" {
+		t.Fatalf("got %q", got)
+	}
+	dt.flushTo(86400, 0)
+	if got := dt.Format("%F %T.%L %a"); got != "1970-01-02 00:00:00.000 Fri" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,205 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	LOG_FORMAT_TEXT = iota //[LEVEL YYYY/MM/DD HH:MM:SS file:line] msg key=value
+	LOG_FORMAT_JSON        //每行一个 JSON 对象
+)
+
+const hex = "0123456789abcdef"
+
+//{"level":"INFO","time":"2006-01-02T15:04:05.000000+08:00","caller":"file.go:10","msg":"...",...}
+func appendJSONEntry(buf *Buffer, dt *datetime, item *Entry, callerMode int) {
+	buf.AppendString(`{"level":"`)
+	appendJSONStringContent(buf, levelOf(item.level).Name)
+	buf.AppendString(`","time":"`)
+	appendISOTime(buf, dt)
+	buf.AppendString(`","caller":"`)
+	appendJSONStringContent(buf, item.frame.File(callerMode))
+	buf.AppendBytes(':')
+	buf.AppendInt(item.frame.line, 0)
+	if item.name != "" {
+		buf.AppendString(`","logger":"`)
+		appendJSONStringContent(buf, item.name)
+	}
+	buf.AppendString(`","msg":`)
+	appendJSONString(buf, item.content)
+	for _, field := range item.fields {
+		buf.AppendBytes(',')
+		appendJSONString(buf, field.Key)
+		buf.AppendBytes(':')
+		appendJSONValue(buf, field.Value)
+	}
+	buf.AppendBytes('}')
+}
+
+func appendISOTime(buf *Buffer, dt *datetime) {
+	buf.AppendInt(dt.year, 4)
+	buf.AppendBytes('-')
+	buf.AppendInt(dt.month, 2)
+	buf.AppendBytes('-')
+	buf.AppendInt(dt.day, 2)
+	buf.AppendBytes('T')
+	buf.AppendInt(dt.hour, 2)
+	buf.AppendBytes(':')
+	buf.AppendInt(dt.min, 2)
+	buf.AppendBytes(':')
+	buf.AppendInt(dt.sec, 2)
+	buf.AppendBytes('.')
+	buf.AppendInt(dt.MicroSec(), 6)
+	appendZoneOffset(buf, dt.offset, true)
+}
+
+//Z 或 ±hh:mm (colon=false 时为 ±hhmm)
+func appendZoneOffset(buf *Buffer, zoneOffset int64, colon bool) {
+	if zoneOffset == 0 && colon {
+		buf.AppendBytes('Z')
+		return
+	}
+	if zoneOffset < 0 {
+		buf.AppendBytes('-')
+		zoneOffset = -zoneOffset
+	} else {
+		buf.AppendBytes('+')
+	}
+	buf.AppendInt(int(zoneOffset/hourSec), 2)
+	if colon {
+		buf.AppendBytes(':')
+	}
+	buf.AppendInt(int(zoneOffset%hourSec/minSec), 2)
+}
+
+func appendJSONValue(buf *Buffer, value interface{}) {
+	switch x := value.(type) {
+	case nil:
+		buf.AppendString("null")
+	case string:
+		appendJSONString(buf, x)
+	case int:
+		*buf = strconv.AppendInt(*buf, int64(x), 10)
+	case int8:
+		*buf = strconv.AppendInt(*buf, int64(x), 10)
+	case int16:
+		*buf = strconv.AppendInt(*buf, int64(x), 10)
+	case int32:
+		*buf = strconv.AppendInt(*buf, int64(x), 10)
+	case int64:
+		*buf = strconv.AppendInt(*buf, x, 10)
+	case uint:
+		*buf = strconv.AppendUint(*buf, uint64(x), 10)
+	case uint8:
+		*buf = strconv.AppendUint(*buf, uint64(x), 10)
+	case uint16:
+		*buf = strconv.AppendUint(*buf, uint64(x), 10)
+	case uint32:
+		*buf = strconv.AppendUint(*buf, uint64(x), 10)
+	case uint64:
+		*buf = strconv.AppendUint(*buf, x, 10)
+	case float32:
+		appendJSONFloat(buf, float64(x), 32)
+	case float64:
+		appendJSONFloat(buf, x, 64)
+	case bool:
+		*buf = strconv.AppendBool(*buf, x)
+	case time.Duration:
+		appendJSONString(buf, x.String())
+	case time.Time:
+		buf.AppendBytes('"')
+		*buf = x.AppendFormat(*buf, time.RFC3339Nano)
+		buf.AppendBytes('"')
+	case json.Marshaler:
+		appendJSONMarshal(buf, x)
+	case error:
+		appendJSONString(buf, x.Error())
+	case fmt.Stringer:
+		appendJSONString(buf, x.String())
+	default:
+		appendJSONMarshal(buf, x)
+	}
+}
+
+//NaN 和 Inf 不是合法的 JSON 数字, 以字符串输出
+func appendJSONFloat(buf *Buffer, f float64, bitSize int) {
+	switch {
+	case math.IsNaN(f):
+		buf.AppendString(`"NaN"`)
+	case math.IsInf(f, 1):
+		buf.AppendString(`"+Inf"`)
+	case math.IsInf(f, -1):
+		buf.AppendString(`"-Inf"`)
+	default:
+		*buf = strconv.AppendFloat(*buf, f, 'g', -1, bitSize)
+	}
+}
+
+func appendJSONMarshal(buf *Buffer, value interface{}) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		appendJSONString(buf, fmt.Sprint(value))
+		return
+	}
+	buf.AppendBytes(b...)
+}
+
+func appendJSONString(buf *Buffer, s string) {
+	buf.AppendBytes('"')
+	appendJSONStringContent(buf, s)
+	buf.AppendBytes('"')
+}
+
+//转义引号,反斜杠,控制字符及非法 UTF-8
+func appendJSONStringContent(buf *Buffer, s string) {
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf.AppendString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.AppendBytes('\\', c)
+			case '\n':
+				buf.AppendBytes('\\', 'n')
+			case '\r':
+				buf.AppendBytes('\\', 'r')
+			case '\t':
+				buf.AppendBytes('\\', 't')
+			default:
+				buf.AppendBytes('\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.AppendString(s[start:i])
+			buf.AppendString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		//U+2028, U+2029 在部分 JavaScript 解析器中视为换行
+		if r == '\u2028' || r == '\u2029' {
+			buf.AppendString(s[start:i])
+			buf.AppendString(`\u202`)
+			buf.AppendBytes(hex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.AppendString(s[start:])
+}
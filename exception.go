@@ -29,23 +29,13 @@ func toError(r interface{}) (err error) {
 	return
 }
 
-func callerShort(skip int) (file string, line int) {
-	var ok bool
-	_, file, line, ok = runtime.Caller(skip)
-	if ok {
-		short := file
-		for i := len(file) - 1; i > 0; i-- {
-			if file[i] == '/' {
-				short = file[i+1:]
-				break
-			}
+func shortFile(file string) string {
+	for i := len(file) - 1; i > 0; i-- {
+		if file[i] == '/' {
+			return file[i+1:]
 		}
-		file = short
-	} else{
-		file = "???"
-		line = 0
 	}
-	return
+	return file
 }
 
 func itoa(dst *[]byte, i int, w int) {
@@ -56,7 +46,6 @@ func itoa(dst *[]byte, i int, w int) {
 		b[pos] = byte(48 + i - m*10)
 		i = m
 		pos--
-		w--
 	}
 	b[pos] = byte(48 + i)
 
@@ -133,7 +122,7 @@ func Exception(catch func(stack string, e error)){
 		myBuf := formatStack(name, file,line, myErr.Error(), info)
 		defer myBuf.free()
 		if catch == nil{
-			log.output(LOG_LEVEL_FATAL, string(*myBuf))
+			log.output(LOG_LEVEL_FATAL, string(*myBuf), nil)
 		} else{
 			catch(string(*myBuf), myErr)
 		}
@@ -0,0 +1,180 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const badKey = "!BADKEY"
+
+//Field 结构化日志字段
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key string, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+//将 key/value 交替的参数转换为字段, 参数中可直接混入 Field
+func toFields(keysAndValues []interface{}) []Field {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+	fields := make([]Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); {
+		switch x := keysAndValues[i].(type) {
+		case Field:
+			fields = append(fields, x)
+			i++
+		case []Field:
+			fields = append(fields, x...)
+			i++
+		case string:
+			if i+1 == len(keysAndValues) {
+				fields = append(fields, Field{Key: badKey, Value: x})
+			} else {
+				fields = append(fields, Field{Key: x, Value: keysAndValues[i+1]})
+			}
+			i += 2
+		default:
+			fields = append(fields, Field{Key: badKey, Value: x})
+			i++
+		}
+	}
+	for i := range fields {
+		if lazy, ok := fields[i].Value.(func() string); ok {
+			fields[i].Value = lazy()
+		}
+	}
+	return fields
+}
+
+//以 key=value 形式追加字段
+func appendTextFields(buf *Buffer, fields []Field) {
+	for _, field := range fields {
+		buf.AppendBytes(' ')
+		appendTextField(buf, field)
+	}
+}
+
+func appendTextField(buf *Buffer, field Field) {
+	appendTextString(buf, field.Key)
+	buf.AppendBytes('=')
+	appendTextValue(buf, field.Value)
+}
+
+func appendTextValue(buf *Buffer, value interface{}) {
+	switch x := value.(type) {
+	case nil:
+		buf.AppendString("<nil>")
+	case string:
+		appendTextString(buf, x)
+	case int:
+		*buf = strconv.AppendInt(*buf, int64(x), 10)
+	case int8:
+		*buf = strconv.AppendInt(*buf, int64(x), 10)
+	case int16:
+		*buf = strconv.AppendInt(*buf, int64(x), 10)
+	case int32:
+		*buf = strconv.AppendInt(*buf, int64(x), 10)
+	case int64:
+		*buf = strconv.AppendInt(*buf, x, 10)
+	case uint:
+		*buf = strconv.AppendUint(*buf, uint64(x), 10)
+	case uint8:
+		*buf = strconv.AppendUint(*buf, uint64(x), 10)
+	case uint16:
+		*buf = strconv.AppendUint(*buf, uint64(x), 10)
+	case uint32:
+		*buf = strconv.AppendUint(*buf, uint64(x), 10)
+	case uint64:
+		*buf = strconv.AppendUint(*buf, x, 10)
+	case float32:
+		*buf = strconv.AppendFloat(*buf, float64(x), 'g', -1, 32)
+	case float64:
+		*buf = strconv.AppendFloat(*buf, x, 'g', -1, 64)
+	case bool:
+		*buf = strconv.AppendBool(*buf, x)
+	case time.Duration:
+		buf.AppendString(x.String())
+	case time.Time:
+		*buf = x.AppendFormat(*buf, time.RFC3339Nano)
+	case error:
+		appendTextString(buf, x.Error())
+	case fmt.Stringer:
+		appendTextString(buf, x.String())
+	default:
+		appendTextString(buf, fmt.Sprint(x))
+	}
+}
+
+//包含空白,引号,等号或不可见字符时加引号输出
+func appendTextString(buf *Buffer, s string) {
+	if needQuote(s) {
+		*buf = strconv.AppendQuote(*buf, s)
+	} else {
+		buf.AppendString(s)
+	}
+}
+
+func needQuote(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || !strconv.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
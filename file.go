@@ -3,6 +3,8 @@ package log
 import (
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 
@@ -61,10 +63,25 @@ type handleFile struct {
 	flag int
 	perm os.FileMode
 	handle *os.File
+	maxSize int64 //单个文件最大字节数, 0不限制
+	size int64 //当前文件已写入字节数
+	index int //同一时间段内的滚动序号, 0表示无后缀
+	opened string //最近一次打开的文件
+	checked int64 //最近一次检查文件是否存在的时间(秒)
+	compressExt string //已压缩的滚动文件后缀, 该序号已被占用
+	onSwitch func(prevPath, path string) //切换到新文件后回调, 首次打开时 prevPath 为空
 }
 
 func (my *handleFile)PathName() string {
-	return filepath.Join(my.folderPath, my.fileName)
+	return filepath.Join(my.folderPath, my.indexFileName())
+}
+
+//fileName, fileName.1, fileName.2 ...
+func (my *handleFile)indexFileName() string {
+	if my.index == 0{
+		return my.fileName
+	}
+	return my.fileName + "." + strconv.Itoa(my.index)
 }
 
 func (my *handleFile)SetPathName(folderPath, fileName string) bool {
@@ -74,9 +91,84 @@ func (my *handleFile)SetPathName(folderPath, fileName string) bool {
 	my.Close()
 	my.folderPath = folderPath
 	my.fileName = fileName
+	my.index = 0
 	return true
 }
 
+func (my *handleFile)SetMaxSize(maxSize int64) {
+	if maxSize < 0{
+		maxSize = 0
+	}
+	my.maxSize = maxSize
+}
+
+//同一时间段内已存在的最大序号, 重启后从该序号继续写入
+func (my *handleFile)lastIndex() int {
+	entries, err := os.ReadDir(my.folderPath)
+	if err != nil{
+		return 0
+	}
+	last := 0
+	prefix := my.fileName + "."
+	for _, entry := range entries{
+		name := entry.Name()
+		if my.compressExt != ""{
+			name = strings.TrimSuffix(name, my.compressExt)
+		}
+		//压缩后的首个文件 x.log.gz 去掉后缀后为 x.log, 比前缀短
+		if len(name) <= len(prefix) || !strings.HasPrefix(name, prefix){
+			continue
+		}
+		index, err := strconv.Atoi(name[len(prefix):])
+		if err == nil && index > last{
+			last = index
+		}
+	}
+	return last
+}
+
+//打开当前序号的文件, 已写满时顺延到下一个序号
+func (my *handleFile)open() (err error) {
+	if my.maxSize > 0 && my.index == 0{
+		my.index = my.lastIndex()
+	}
+	for {
+		if my.compressExt != ""{
+			if has, _ := exist(my.PathName() + my.compressExt); has{
+				my.index++
+				continue
+			}
+		}
+		my.handle, err = openFile(my.folderPath, my.indexFileName(), my.flag, my.perm)
+		if err != nil{
+			return
+		}
+		my.size = 0
+		my.checked, _ = now()
+		if info, e := my.handle.Stat(); e == nil{
+			my.size = info.Size()
+		}
+		if my.maxSize == 0 || my.size < my.maxSize{
+			my.switched()
+			return
+		}
+		my.Close()
+		my.index++
+	}
+}
+
+func (my *handleFile)switched() {
+	path := my.PathName()
+	if path == my.opened{
+		return
+	}
+	prevPath := my.opened
+	my.opened = path
+	if my.onSwitch != nil{
+		my.onSwitch(prevPath, path)
+	}
+}
+
 func (my *handleFile)WriteString(s string) (n int, err error){
 	n, err = my.Write([]byte(s))
 	return
@@ -84,22 +176,33 @@ func (my *handleFile)WriteString(s string) (n int, err error){
 
 func (my *handleFile)Write(b []byte) (n int, err error){
 	if my.handle == nil{
-		my.handle, err = openFile(my.folderPath,my.fileName,my.flag,my.perm)
+		err = my.open()
 		if err != nil{
 			return
 		}
-	} else {
+	} else if sec, _ := now(); sec != my.checked{
+		//文件被删除或移走时重新打开, 每秒最多检查一次, 滚动只依赖已写入的字节数
+		my.checked = sec
 		var hasLog bool
-		hasLog, err = exist(filepath.Join(my.folderPath, my.fileName))
+		hasLog, err = exist(my.PathName())
 		if !hasLog{
-			my.handle.Close()
-			my.handle, err = openFile(my.folderPath,my.fileName,my.flag,my.perm)
+			my.Close()
+			err = my.open()
 			if err != nil{
 				return
 			}
 		}
 	}
+	for my.maxSize > 0 && my.size > 0 && my.size+int64(len(b)) > my.maxSize{
+		my.Close()
+		my.index++
+		err = my.open()
+		if err != nil{
+			return
+		}
+	}
 	n, err = my.handle.Write(b)
+	my.size += int64(n)
 	return
 }
 
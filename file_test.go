@@ -0,0 +1,87 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func touch(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//重启后首个文件已压缩为 x.log.gz, 序号从已有的最大序号继续
+func TestHandleFileRestartAfterCompress(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, filepath.Join(dir, "x.log.gz"), 10)
+	touch(t, filepath.Join(dir, "x.log.1.gz"), 10)
+	touch(t, filepath.Join(dir, "x.log.2"), 10)
+	touch(t, filepath.Join(dir, "x.log.bak"), 10)
+	touch(t, filepath.Join(dir, "x.log"+".gz.tmp"), 10)
+
+	file := &handleFile{flag: os.O_WRONLY | os.O_APPEND | os.O_CREATE, perm: 0644, maxSize: 100, compressExt: ".gz"}
+	file.SetPathName(dir, "x.log")
+	if last := file.lastIndex(); last != 2 {
+		t.Fatalf("lastIndex = %d, want 2", last)
+	}
+	if _, err := file.Write([]byte("0123456789\n")); err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if want := filepath.Join(dir, "x.log.2"); file.PathName() != want {
+		t.Fatalf("writing to %s, want %s", file.PathName(), want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x.log")); !os.IsNotExist(err) {
+		t.Fatalf("x.log recreated after it was compressed: %v", err)
+	}
+}
+
+//已压缩的序号被跳过, 写满后顺延
+func TestHandleFileSkipCompressedIndex(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, filepath.Join(dir, "x.log.gz"), 10)
+	touch(t, filepath.Join(dir, "x.log.1"), 100)
+
+	file := &handleFile{flag: os.O_WRONLY | os.O_APPEND | os.O_CREATE, perm: 0644, maxSize: 100, compressExt: ".gz"}
+	file.SetPathName(dir, "x.log")
+	if _, err := file.Write([]byte("0123456789\n")); err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if want := filepath.Join(dir, "x.log.2"); file.PathName() != want {
+		t.Fatalf("writing to %s, want %s", file.PathName(), want)
+	}
+}
+
+//同一秒内不检查文件是否存在, 之后发现文件被删除时重新创建
+func TestHandleFileRecreateRemoved(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "x.log")
+	file := &handleFile{flag: os.O_WRONLY | os.O_APPEND | os.O_CREATE, perm: 0644}
+	file.SetPathName(dir, "x.log")
+	defer file.Close()
+	if _, err := file.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	sec, _ := now()
+	file.checked = sec
+	if _, err := file.Write([]byte("lost\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) && file.checked == sec {
+		t.Fatalf("file checked within the same second: %v", err)
+	}
+	file.checked = 0
+	if _, err := file.Write([]byte("second\n")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "second\n" {
+		t.Fatalf("recreated file = %q, %v", data, err)
+	}
+}
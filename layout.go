@@ -0,0 +1,189 @@
+package log
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+const defaultLayoutTime = "%Y/%m/%d %H:%M:%S.%L"
+
+const (
+	layoutText      = iota //原样输出
+	layoutLevel            //%L 等级
+	layoutTime             //%D{...} 时间, 括号内为 strftime 格式
+	layoutFile             //%F 文件, 按 writer 的 caller 模式输出
+	layoutShort            //%S 文件名
+	layoutRelative         //%R 相对模块根目录的路径
+	layoutPath             //%P 文件完整路径
+	layoutLine             //%l 行号
+	layoutFunc             //%fn 函数名
+	layoutPackage          //%pkg 包路径
+	layoutGoroutine        //%gid 协程ID
+	layoutPid              //%pid 进程ID
+	layoutHost             //%host 主机名
+	layoutMessage          //%m 日志内容
+	layoutName             //%n logger 名称
+	layoutFields           //%fields 结构化字段 key=value
+)
+
+//按长度从长到短匹配
+var layoutVerbs = []struct {
+	name string
+	kind int
+}{
+	{"fields", layoutFields},
+	{"host", layoutHost},
+	{"gid", layoutGoroutine},
+	{"pid", layoutPid},
+	{"pkg", layoutPackage},
+	{"fn", layoutFunc},
+	{"D", layoutTime},
+	{"L", layoutLevel},
+	{"F", layoutFile},
+	{"S", layoutShort},
+	{"R", layoutRelative},
+	{"P", layoutPath},
+	{"l", layoutLine},
+	{"m", layoutMessage},
+	{"n", layoutName},
+}
+
+type layoutPart struct {
+	kind int
+	text string
+}
+
+//编译后的布局, 如 "[%L %D{%Y-%m-%d %H:%M:%S.%f} %F:%l %fn] %m"
+//布局中没有 %fields 时字段追加在行尾
+type layout struct {
+	parts         []layoutPart
+	hasFields     bool
+	needGoroutine bool
+}
+
+func compileLayout(s string) (*layout, error) {
+	out := &layout{}
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			out.parts = append(out.parts, layoutPart{kind: layoutText, text: text.String()})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		if s[i] != '%' || i+1 == len(s) {
+			text.WriteByte(s[i])
+			i++
+			continue
+		}
+		if s[i+1] == '%' {
+			text.WriteByte('%')
+			i += 2
+			continue
+		}
+		matched := false
+		for _, verb := range layoutVerbs {
+			if !strings.HasPrefix(s[i+1:], verb.name) {
+				continue
+			}
+			flush()
+			i += 1 + len(verb.name)
+			part := layoutPart{kind: verb.kind}
+			if verb.kind == layoutTime {
+				part.text = defaultLayoutTime
+				if i < len(s) && s[i] == '{' {
+					end := strings.IndexByte(s[i:], '}')
+					if end == -1 {
+						return nil, newError("layout: unterminated %%D{ in %q", s)
+					}
+					part.text = s[i+1 : i+end]
+					i += end + 1
+				}
+			}
+			out.hasFields = out.hasFields || verb.kind == layoutFields
+			out.needGoroutine = out.needGoroutine || verb.kind == layoutGoroutine
+			out.parts = append(out.parts, part)
+			matched = true
+			break
+		}
+		if !matched {
+			text.WriteByte(s[i])
+			i++
+		}
+	}
+	flush()
+	return out, nil
+}
+
+func (my *layout) render(buf *Buffer, dt *datetime, item *Entry, callerMode int, label string) {
+	for _, part := range my.parts {
+		switch part.kind {
+		case layoutText:
+			buf.AppendString(part.text)
+		case layoutLevel:
+			buf.AppendString(label)
+		case layoutTime:
+			dt.appendFormat(buf, part.text)
+		case layoutFile:
+			buf.AppendString(item.frame.File(callerMode))
+		case layoutShort:
+			buf.AppendString(item.frame.file)
+		case layoutRelative:
+			buf.AppendString(item.frame.relative)
+		case layoutPath:
+			buf.AppendString(item.frame.path)
+		case layoutLine:
+			buf.AppendInt(item.frame.line, 0)
+		case layoutFunc:
+			buf.AppendString(item.frame.funcName)
+		case layoutPackage:
+			buf.AppendString(item.frame.pkg)
+		case layoutGoroutine:
+			buf.AppendInt(int(item.goid), 0)
+		case layoutPid:
+			buf.AppendInt(pid, 0)
+		case layoutHost:
+			buf.AppendString(hostname)
+		case layoutMessage:
+			buf.AppendString(item.content)
+		case layoutName:
+			buf.AppendString(item.name)
+		case layoutFields:
+			for i, field := range item.fields {
+				if i > 0 {
+					buf.AppendBytes(' ')
+				}
+				appendTextField(buf, field)
+			}
+		}
+	}
+	if !my.hasFields {
+		appendTextFields(buf, item.fields)
+	}
+}
+
+//从栈信息 "goroutine 18 [running]:" 中解析协程ID
+func goroutineID() int64 {
+	var b [64]byte
+	stack := b[:runtime.Stack(b[:], false)]
+	stack = stack[len("goroutine "):]
+	var id int64
+	for _, c := range stack {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + int64(c-'0')
+	}
+	return id
+}
+
+var pid = os.Getpid()
+
+var hostname = func() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "???"
+	}
+	return name
+}()
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//日志等级信息
+type Level struct {
+	Value int    //等级数值, 越大越严重
+	Name  string //名称, 用于 JSON 输出及解析, 如 NOTICE
+	Label string //简写, 用于文本输出, 如 NOTI
+	Color string //ANSI 颜色代码, 如 "33" 黄色, "1;31" 加粗红色; 空为不着色
+
+	colored string //带颜色的简写
+}
+
+//各等级信息的快照, 注册时整体替换, 读取无锁
+type levelTable struct {
+	byValue map[int]*Level
+	byName  map[string]*Level //大写的名称及简写
+}
+
+var levels atomic.Value
+var levelsMu sync.Mutex
+
+func init() {
+	levels.Store(&levelTable{byValue: map[int]*Level{}, byName: map[string]*Level{}})
+	for _, level := range []Level{
+		{Value: LOG_LEVEL_TRACE, Name: "TRACE", Label: "TRAC", Color: "90"},
+		{Value: LOG_LEVEL_DEBUG, Name: "DEBUG", Label: "DEBU", Color: "36"},
+		{Value: LOG_LEVEL_INFO, Name: "INFO", Label: "INFO", Color: "32"},
+		{Value: LOG_LEVEL_WARN, Name: "WARN", Label: "WARN", Color: "33"},
+		{Value: LOG_LEVEL_ERROR, Name: "ERROR", Label: "ERRO", Color: "31"},
+		{Value: LOG_LEVEL_FATAL, Name: "FATAL", Label: "CRIT", Color: "1;31"},
+	} {
+		if err := RegisterLevel(level); err != nil {
+			panic(err)
+		}
+	}
+}
+
+//注册自定义等级, 如 RegisterLevel(Level{Value: 35, Name: "NOTICE", Label: "NOTI", Color: "34"})
+//数值须在 LOG_LEVEL_TRACE 与 LOG_LEVEL_OFF 之间(不含), 数值及名称不能与已注册的等级重复
+func RegisterLevel(level Level) error {
+	if level.Value < LOG_LEVEL_TRACE || level.Value >= LOG_LEVEL_OFF {
+		return newError("register level %s error: value %d out of range [%d, %d)", level.Name, level.Value, LOG_LEVEL_TRACE, LOG_LEVEL_OFF)
+	}
+	if level.Name == "" {
+		return newError("register level %d error: empty name", level.Value)
+	}
+	if level.Label == "" {
+		level.Label = level.Name
+	}
+	level.colored = level.Label
+	if level.Color != "" {
+		level.colored = "\x1b[" + level.Color + "m" + level.Label + "\x1b[0m"
+	}
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	old := levels.Load().(*levelTable)
+	if exists, ok := old.byValue[level.Value]; ok {
+		return newError("register level %s error: value %d already used by %s", level.Name, level.Value, exists.Name)
+	}
+	name, label := strings.ToUpper(level.Name), strings.ToUpper(level.Label)
+	for _, key := range []string{name, label} {
+		if _, ok := old.byName[key]; ok || key == "OFF" {
+			return newError("register level %s error: name %s already used", level.Name, key)
+		}
+	}
+	table := &levelTable{byValue: make(map[int]*Level, len(old.byValue)+1), byName: make(map[string]*Level, len(old.byName)+2)}
+	for k, v := range old.byValue {
+		table.byValue[k] = v
+	}
+	for k, v := range old.byName {
+		table.byName[k] = v
+	}
+	table.byValue[level.Value] = &level
+	table.byName[label] = &level
+	table.byName[name] = &level
+	levels.Store(table)
+	return nil
+}
+
+//返回等级信息, 未注册的等级返回 false
+func LookupLevel(value int) (Level, bool) {
+	level, ok := levels.Load().(*levelTable).byValue[value]
+	if !ok {
+		return Level{}, false
+	}
+	return *level, true
+}
+
+//旧版本 DEBUG-OFF 的数值为 0-5, 之后改为留有间隔的 20-100
+var legacyLevels = [...]int{LOG_LEVEL_DEBUG, LOG_LEVEL_INFO, LOG_LEVEL_WARN, LOG_LEVEL_ERROR, LOG_LEVEL_FATAL, LOG_LEVEL_OFF}
+
+//将旧版本的等级数值转换为当前数值, 超出 LOG_LEVEL_TRACE-LOG_LEVEL_OFF 范围时返回错误
+func normalizeLevel(level int) (int, error) {
+	if level >= 0 && level < len(legacyLevels) {
+		return legacyLevels[level], nil
+	}
+	if level < LOG_LEVEL_TRACE || level > LOG_LEVEL_OFF {
+		return level, newError("invalid log level: %d", level)
+	}
+	return level, nil
+}
+
+//未注册的等级输出为 LEVEL(37)
+func levelOf(value int) *Level {
+	if level, ok := levels.Load().(*levelTable).byValue[value]; ok {
+		return level
+	}
+	name := "LEVEL(" + strconv.Itoa(value) + ")"
+	return &Level{Value: value, Name: name, Label: name, colored: name}
+}
+
+//按名称或简写(不区分大小写)解析等级, 也支持 "off" 及数值
+func ParseLevel(s string) (int, error) {
+	key := strings.ToUpper(strings.TrimSpace(s))
+	if key == "OFF" {
+		return LOG_LEVEL_OFF, nil
+	}
+	if level, ok := levels.Load().(*levelTable).byName[key]; ok {
+		return level.Value, nil
+	}
+	if value, err := strconv.Atoi(key); err == nil {
+		return normalizeLevel(value)
+	}
+	return 0, newError("unknown log level: %q", s)
+}
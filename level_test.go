@@ -0,0 +1,77 @@
+package log
+
+import "testing"
+
+//旧版本的等级数值 0-5 仍然有效, 超出范围的返回错误且不修改等级
+func TestSetLevelLegacyValues(t *testing.T) {
+	logger := NewLogger(0, false)
+	if logger.level != LOG_LEVEL_DEBUG {
+		t.Fatalf("NewLogger(0) level = %d, want LOG_LEVEL_DEBUG", logger.level)
+	}
+	for value, want := range []int{LOG_LEVEL_DEBUG, LOG_LEVEL_INFO, LOG_LEVEL_WARN, LOG_LEVEL_ERROR, LOG_LEVEL_FATAL, LOG_LEVEL_OFF} {
+		if err := logger.SetLevel(value); err != nil || logger.level != want {
+			t.Errorf("SetLevel(%d) = %v, level %d, want %d", value, err, logger.level, want)
+		}
+	}
+	for _, value := range []int{-1, 6, 9, 101} {
+		if err := logger.SetLevel(value); err == nil || logger.level != LOG_LEVEL_OFF {
+			t.Errorf("SetLevel(%d) = %v, level %d; want error and level unchanged", value, err, logger.level)
+		}
+	}
+	if err := logger.SetLevel(LOG_LEVEL_TRACE); err != nil || !logger.Enabled(LOG_LEVEL_TRACE) {
+		t.Errorf("SetLevel(LOG_LEVEL_TRACE) = %v", err)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		s     string
+		level int
+		ok    bool
+	}{
+		{"trace", LOG_LEVEL_TRACE, true},
+		{" Debug ", LOG_LEVEL_DEBUG, true},
+		{"ERRO", LOG_LEVEL_ERROR, true},
+		{"crit", LOG_LEVEL_FATAL, true},
+		{"off", LOG_LEVEL_OFF, true},
+		{"1", LOG_LEVEL_INFO, true},
+		{"40", LOG_LEVEL_WARN, true},
+		{"7", 0, false},
+		{"verbose", 0, false},
+	}
+	for _, test := range tests {
+		level, err := ParseLevel(test.s)
+		if (err == nil) != test.ok || (test.ok && level != test.level) {
+			t.Errorf("ParseLevel(%q) = %d, %v", test.s, level, err)
+		}
+	}
+}
+
+func TestRegisterLevel(t *testing.T) {
+	saved := levels.Load()
+	t.Cleanup(func() { levels.Store(saved) })
+	if err := RegisterLevel(Level{Value: 45, Name: "SECURITY", Label: "SECU", Color: "35"}); err != nil {
+		t.Fatal(err)
+	}
+	if level, err := ParseLevel("security"); err != nil || level != 45 {
+		t.Fatalf("ParseLevel(security) = %d, %v", level, err)
+	}
+	if got := levelOf(45).colored; got != "\x1b[35mSECU\x1b[0m" {
+		t.Fatalf("colored label = %q", got)
+	}
+	for _, level := range []Level{
+		{Value: 45, Name: "OTHER"},
+		{Value: 46, Name: "security"},
+		{Value: 47, Name: "X", Label: "warn"},
+		{Value: 48, Name: "OFF"},
+		{Value: 5, Name: "LOW"},
+		{Value: LOG_LEVEL_OFF, Name: "HIGH"},
+	} {
+		if err := RegisterLevel(level); err == nil {
+			t.Errorf("RegisterLevel(%+v) succeeded, want error", level)
+		}
+	}
+	if got := levelOf(47).Name; got != "LEVEL(47)" {
+		t.Fatalf("unregistered level name = %q", got)
+	}
+}
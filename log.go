@@ -1,65 +1,292 @@
 package log
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
 
 const oSkip = logSkip
 
-func SetLevel(level int) {
-	log.SetLevel(level)
+func SetLevel(level int) error {
+	return log.SetLevel(level)
 }
 
 func AddHandler(handler logWriter) {
 	log.AddHandler(handler)
 }
 
+//返回附带字段的全局子 logger
+func With(keysAndValues ...interface{}) *stdLogger {
+	return log.With(keysAndValues...)
+}
+
+//返回指定名称的全局子 logger
+func Named(name string) *stdLogger {
+	return log.Named(name)
+}
+
+//返回额外跳过 skip 层调用的全局 logger
+func WithCallerSkip(skip int) *stdLogger {
+	return log.WithCallerSkip(skip)
+}
+
 func SetAsync(async bool) {
 	log.SetAsync(async)
 }
 
+func Enabled(level int) bool {
+	return log.Enabled(level)
+}
+
+//按指定等级输出, 用于自定义等级
+func Log(level int, v ...interface{}) {
+	if log.Enabled(level) {
+		log.output(level, fmt.Sprint(lazyArgs(v)...), nil)
+	}
+}
+
+func LogF(level int, format string, v ...interface{}) {
+	if log.Enabled(level) {
+		log.output(level, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
+}
+
+func LogW(level int, msg string, keysAndValues ...interface{}) {
+	if log.Enabled(level) {
+		log.output(level, msg, toFields(keysAndValues))
+	}
+}
+
+func Trace(v ...interface{}) {
+	if log.Enabled(LOG_LEVEL_TRACE) {
+		log.output(LOG_LEVEL_TRACE, fmt.Sprint(lazyArgs(v)...), nil)
+	}
+}
+
 func Debug(v ...interface{}) {
-	log.output(LOG_LEVEL_DEBUG, fmt.Sprint(v...))
+	if log.Enabled(LOG_LEVEL_DEBUG) {
+		log.output(LOG_LEVEL_DEBUG, fmt.Sprint(lazyArgs(v)...), nil)
+	}
 }
 
 func Info(v ...interface{}) {
-	log.output(LOG_LEVEL_INFO, fmt.Sprint(v...))
+	if log.Enabled(LOG_LEVEL_INFO) {
+		log.output(LOG_LEVEL_INFO, fmt.Sprint(lazyArgs(v)...), nil)
+	}
 }
 
 func Warn(v ...interface{}) {
-	log.output(LOG_LEVEL_WARN, fmt.Sprint(v...))
+	if log.Enabled(LOG_LEVEL_WARN) {
+		log.output(LOG_LEVEL_WARN, fmt.Sprint(lazyArgs(v)...), nil)
+	}
 }
 
 func Error(v ...interface{}) {
-	log.output(LOG_LEVEL_ERROR, fmt.Sprint(v...))
+	if log.Enabled(LOG_LEVEL_ERROR) {
+		log.output(LOG_LEVEL_ERROR, fmt.Sprint(lazyArgs(v)...), nil)
+	}
 }
 
 func Fatal(v ...interface{}) {
-	log.output(LOG_LEVEL_FATAL, fmt.Sprint(v...))
+	if log.Enabled(LOG_LEVEL_FATAL) {
+		log.output(LOG_LEVEL_FATAL, fmt.Sprint(lazyArgs(v)...), nil)
+	}
+}
+
+func TraceF(format string, v ...interface{}) {
+	if log.Enabled(LOG_LEVEL_TRACE) {
+		log.output(LOG_LEVEL_TRACE, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
 }
 
 func DebugF(format string, v ...interface{}) {
-	log.output(LOG_LEVEL_DEBUG, fmt.Sprintf(format, v...))
+	if log.Enabled(LOG_LEVEL_DEBUG) {
+		log.output(LOG_LEVEL_DEBUG, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
 }
 
 func InfoF(format string, v ...interface{}) {
-	log.output(LOG_LEVEL_INFO, fmt.Sprintf(format, v...))
+	if log.Enabled(LOG_LEVEL_INFO) {
+		log.output(LOG_LEVEL_INFO, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
 }
 
 func WarnF(format string, v ...interface{}) {
-	log.output(LOG_LEVEL_WARN, fmt.Sprintf(format, v...))
+	if log.Enabled(LOG_LEVEL_WARN) {
+		log.output(LOG_LEVEL_WARN, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
 }
 
 func ErrorF(format string, v ...interface{}) {
-	log.output(LOG_LEVEL_ERROR, fmt.Sprintf(format, v...))
+	if log.Enabled(LOG_LEVEL_ERROR) {
+		log.output(LOG_LEVEL_ERROR, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
 }
 
 func FatalF(format string, v ...interface{}) {
-	log.output(LOG_LEVEL_FATAL, fmt.Sprintf(format, v...))
+	if log.Enabled(LOG_LEVEL_FATAL) {
+		log.output(LOG_LEVEL_FATAL, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
+}
+
+func TraceW(msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_TRACE) {
+		log.output(LOG_LEVEL_TRACE, msg, toFields(keysAndValues))
+	}
+}
+
+func DebugW(msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_DEBUG) {
+		log.output(LOG_LEVEL_DEBUG, msg, toFields(keysAndValues))
+	}
+}
+
+func InfoW(msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_INFO) {
+		log.output(LOG_LEVEL_INFO, msg, toFields(keysAndValues))
+	}
+}
+
+func WarnW(msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_WARN) {
+		log.output(LOG_LEVEL_WARN, msg, toFields(keysAndValues))
+	}
+}
+
+func ErrorW(msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_ERROR) {
+		log.output(LOG_LEVEL_ERROR, msg, toFields(keysAndValues))
+	}
+}
+
+func FatalW(msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_FATAL) {
+		log.output(LOG_LEVEL_FATAL, msg, toFields(keysAndValues))
+	}
+}
+
+//按 context 输出, 附带 RegisterContextExtractor 从 ctx 中提取的字段, 如 trace_id, request_id
+func LogCtx(ctx context.Context, level int, msg string, keysAndValues ...interface{}) {
+	if log.Enabled(level) {
+		log.output(level, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func TraceCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_TRACE) {
+		log.output(LOG_LEVEL_TRACE, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func DebugCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_DEBUG) {
+		log.output(LOG_LEVEL_DEBUG, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_INFO) {
+		log.output(LOG_LEVEL_INFO, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_WARN) {
+		log.output(LOG_LEVEL_WARN, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_ERROR) {
+		log.output(LOG_LEVEL_ERROR, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func FatalCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if log.Enabled(LOG_LEVEL_FATAL) {
+		log.output(LOG_LEVEL_FATAL, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+//输出 FATAL 日志并等待写入后 panic
+func Panic(v ...interface{}) {
+	content := fmt.Sprint(lazyArgs(v)...)
+	log.output(LOG_LEVEL_FATAL, content, nil)
+	log.Flush()
+	panic(content)
+}
+
+func PanicF(format string, v ...interface{}) {
+	content := fmt.Sprintf(format, lazyArgs(v)...)
+	log.output(LOG_LEVEL_FATAL, content, nil)
+	log.Flush()
+	panic(content)
+}
+
+//输出 FATAL 日志, 等待所有异步日志写入后退出进程
+func Exit(v ...interface{}) {
+	log.output(LOG_LEVEL_FATAL, fmt.Sprint(lazyArgs(v)...), nil)
+	Wait()
+	os.Exit(1)
+}
+
+func ExitF(format string, v ...interface{}) {
+	log.output(LOG_LEVEL_FATAL, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	Wait()
+	os.Exit(1)
 }
 
 func SetFormatHeader(formatHeader func(buf *Buffer, level string, line int, file string, dt DateTime)) {
 	log.SetFormatHeader(formatHeader)
 }
 
+func SetQueueCapacity(capacity int, overflow int) {
+	log.SetQueueCapacity(capacity, overflow)
+}
+
+func SetBatch(maxSize int, maxLatency time.Duration) {
+	log.SetBatch(maxSize, maxLatency)
+}
+
+func Dropped() uint64 {
+	return log.Dropped()
+}
+
+func SetLayout(layout string) error {
+	return log.SetLayout(layout)
+}
+
+func SetVModule(spec string) error {
+	return log.SetVModule(spec)
+}
+
+func SetColor(color bool) {
+	log.SetColor(color)
+}
+
+func SetCallerMode(mode int) {
+	log.SetCallerMode(mode)
+}
+
+func SetFormat(format int) {
+	log.SetFormat(format)
+}
+
+func SetClock(clock Clock) {
+	log.SetClock(clock)
+}
+
+func SetLocation(loc *time.Location) {
+	log.SetLocation(loc)
+}
+
+//等待异步日志写入完成, 不停止异步写入
+func Flush() {
+	log.Flush()
+}
+
 func Wait() { //等待异步日志模块退出
 	loggerMgr.Wait()
 	log.Wait()
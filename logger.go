@@ -1,6 +1,8 @@
 package log
 
 import (
+	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -8,37 +10,153 @@ import (
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 	_ "unsafe"
 )
 
+//等级之间留有间隔, 以便用 RegisterLevel 注册自定义等级
 const (
-	LOG_LEVEL_DEBUG = iota
-	LOG_LEVEL_INFO
-	LOG_LEVEL_WARN
-	LOG_LEVEL_ERROR
-	LOG_LEVEL_FATAL
-	LOG_LEVEL_OFF
+	LOG_LEVEL_TRACE = 10
+	LOG_LEVEL_DEBUG = 20
+	LOG_LEVEL_INFO  = 30
+	LOG_LEVEL_WARN  = 40
+	LOG_LEVEL_ERROR = 50
+	LOG_LEVEL_FATAL = 60
+	LOG_LEVEL_OFF   = 100
+)
+
+//异步队列已满时的处理方式
+const (
+	LOG_OVERFLOW_BLOCK       = iota //阻塞调用者直到队列有空位
+	LOG_OVERFLOW_DROP_NEWEST        //丢弃新日志
+	LOG_OVERFLOW_DROP_OLDEST        //丢弃队列中最早的日志
+	LOG_OVERFLOW_SYNC               //同步写入
 )
 
 const logSkip = 3
 
-var logLevels = []string{"DEBU", "INFO", "WARN", "ERRO", "CRIT"}
+const defaultBatchSize = 128
 
+//自定义 writer 只需实现 Write 及 Close, entry 在 Write 返回后回收, 不能保留;
+//SetFormatHeader, SetFormat, SetLayout, SetCallerMode, SetColor, SetLocation 为可选方法, 实现时由 logger 统一设置
 type logWriter interface {
-	Write(unix int64, level int, file string, line int, content string)
-	SetFormatHeader(formatHeader func(buf *Buffer, level string, line int, file string, dt DateTime))
+	Write(entry *Entry)
 	Close()
 }
 
+//可批量写入的 logWriter, 异步模式下一次写入一批日志
+type logBatchWriter interface {
+	WriteBatch(entries []*Entry)
+}
+
 type logBaseWriter struct {
 	time         datetime
 	customHeader func(buf *Buffer, level string, line int, file string, dt DateTime)
+	format       int
+	layout       *layout
+	callerMode   int
+	color        bool
+	level        int //最低等级, 低于该等级的日志不写入
+	filter       func(level int, file string, line int, content string, fields []Field) bool
+	onError      func(err error)
 }
 
 func (my *logBaseWriter) Close() {}
 
+//设置时区, 如 time.UTC; nil 为本地时区
+func (my *logBaseWriter) SetLocation(loc *time.Location) {
+	my.time.SetLocation(loc)
+}
+
+//设置错误回调, 不设置时输出到 os.Stderr; 可能在后台协程中调用
+func (my *logBaseWriter) SetErrorHandler(onError func(err error)) {
+	my.onError = onError
+}
+
+func (my *logBaseWriter) reportError(err error) {
+	if my.onError == nil {
+		fmt.Fprintf(os.Stderr, "log writer error: %v\n", err)
+	} else {
+		my.onError(err)
+	}
+}
+
 func (my *logBaseWriter) SetFormatHeader(formatHeader func(buf *Buffer, level string, line int, file string, dt DateTime)) {
 	my.customHeader = formatHeader
+	my.layout = nil
+}
+
+//设置文本格式的布局, 替换默认头部及自定义头部:
+//%L 等级, %D{%Y-%m-%d %H:%M:%S.%f} 时间, %F 文件(按 caller 模式), %S 文件名, %R 相对路径, %P 完整路径,
+//%l 行号, %fn 函数名, %pkg 包路径,
+//%gid 协程ID, %pid 进程ID, %host 主机名, %m 日志内容, %n logger 名称, %fields 结构化字段(未指定时追加在行尾), %% 百分号
+func (my *logBaseWriter) SetLayout(layout string) error {
+	if layout == "" {
+		my.layout = nil
+		return nil
+	}
+	compiled, err := compileLayout(layout)
+	if err != nil {
+		return err
+	}
+	my.layout = compiled
+	my.customHeader = nil
+	return nil
+}
+
+//LOG_CALLER_SHORT, LOG_CALLER_RELATIVE 或 LOG_CALLER_FULL
+func (my *logBaseWriter) SetCallerMode(mode int) {
+	if mode < LOG_CALLER_SHORT || mode > LOG_CALLER_FULL {
+		return
+	}
+	my.callerMode = mode
+}
+
+//设置该 writer 的最低等级, 在 logger 等级过滤之后生效, 如标准输出 INFO 以上, 错误文件只写 ERROR 以上
+func (my *logBaseWriter) SetLevel(level int) error {
+	level, err := normalizeLevel(level)
+	if err != nil {
+		return err
+	}
+	my.level = level
+	return nil
+}
+
+//设置过滤函数, 返回 false 的日志不写入该 writer; file 为完整路径; nil 为不过滤
+func (my *logBaseWriter) SetFilter(filter func(level int, file string, line int, content string, fields []Field) bool) {
+	my.filter = filter
+}
+
+func (my *logBaseWriter) accept(item *Entry) bool {
+	if item.level < my.level {
+		return false
+	}
+	return my.filter == nil || my.filter(item.level, item.frame.path, item.frame.line, item.content, item.fields)
+}
+
+//文本格式的等级简写按等级颜色输出(ANSI 转义码), 适用于终端
+func (my *logBaseWriter) SetColor(color bool) {
+	my.color = color
+}
+
+func (my *logBaseWriter) levelLabel(level int) string {
+	if my.color {
+		return levelOf(level).colored
+	}
+	return levelOf(level).Label
+}
+
+func (my *logBaseWriter) needGoroutine() bool {
+	layout := my.layout
+	return layout != nil && layout.needGoroutine
+}
+
+//LOG_FORMAT_TEXT 或 LOG_FORMAT_JSON
+func (my *logBaseWriter) SetFormat(format int) {
+	if format < LOG_FORMAT_TEXT || format > LOG_FORMAT_JSON {
+		return
+	}
+	my.format = format
 }
 
 func (my *logBaseWriter) formatHeader(buf *Buffer, level string, line int, file string) {
@@ -63,35 +181,95 @@ func (my *logBaseWriter) formatHeader(buf *Buffer, level string, line int, file
 	buf.AppendString("]")
 }
 
-func (my *logBaseWriter) write(writer io.Writer, level int, file string, line int, content string) {
+func (my *logBaseWriter) write(writer io.Writer, item *Entry) {
 	//buf := make([]byte, 0, 40+len(file)+len(content))
-	buf := getBuffer(40 + len(file) + len(content))
+	buf := getBuffer(itemSize(item))
 	defer buf.free()
-	if my.customHeader == nil {
-		my.formatHeader(buf, logLevels[level], line, file)
-	} else {
-		my.customHeader(buf, logLevels[level], line, file, &my.time)
-	}
-	buf.AppendBytes(' ')
-	buf.AppendString(content)
-	buf.AppendBytes('\n')
+	my.encode(buf, item)
 	//buf = append(buf, content...)
 	//buf = append(buf, '\n')
 	n, err := writer.Write(*buf)
 
 	if err != nil {
-		fmt.Printf("logBaseWriter write error: %v, n=%v\n", err, n)
+		my.reportError(newError("logBaseWriter write error: %v, n=%v", err, n))
+	}
+}
+
+//buf 中前 n 个字节写入 writer, 剩余部分移到 buf 开头
+func (my *logBaseWriter) flush(writer io.Writer, buf *Buffer, n int) {
+	if n == 0 {
+		return
+	}
+	wn, err := writer.Write((*buf)[:n])
+	if err != nil {
+		my.reportError(newError("logBaseWriter write error: %v, n=%v", err, wn))
+	}
+	*buf = append((*buf)[:0], (*buf)[n:]...)
+}
+
+func itemSize(item *Entry) int {
+	return 40 + len(item.frame.path) + len(item.name) + len(item.content) + 32*len(item.fields)
+}
+
+func itemsSize(items []*Entry) int {
+	size := 0
+	for _, item := range items {
+		size += itemSize(item)
+	}
+	return size
+}
+
+func (my *logBaseWriter) encode(buf *Buffer, item *Entry) {
+	if my.format == LOG_FORMAT_JSON {
+		appendJSONEntry(buf, &my.time, item, my.callerMode)
+	} else if my.layout != nil {
+		my.layout.render(buf, &my.time, item, my.callerMode, my.levelLabel(item.level))
+	} else {
+		if my.customHeader == nil {
+			my.formatHeader(buf, my.levelLabel(item.level), item.frame.line, item.frame.File(my.callerMode))
+		} else {
+			my.customHeader(buf, my.levelLabel(item.level), item.frame.line, item.frame.File(my.callerMode), &my.time)
+		}
+		buf.AppendBytes(' ')
+		if item.name != "" {
+			buf.AppendString(item.name)
+			buf.AppendString(": ")
+		}
+		buf.AppendString(item.content)
+		appendTextFields(buf, item.fields)
 	}
+	buf.AppendBytes('\n')
 }
 
 type logStdWriter struct {
 	logBaseWriter
 	writer io.Writer
+	mu     sync.Mutex
 }
 
-func (my *logStdWriter) Write(unix int64, level int, file string, line int, content string) {
-	my.time.flushTo(unix)
-	my.write(my.writer, level, file, line, content)
+func (my *logStdWriter) Write(item *Entry) {
+	if !my.accept(item) {
+		return
+	}
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.time.flushTo(item.unix, item.nsec)
+	my.write(my.writer, item)
+}
+
+func (my *logStdWriter) WriteBatch(items []*Entry) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	buf := getBuffer(itemsSize(items))
+	defer buf.free()
+	for _, item := range items {
+		if !my.accept(item) {
+			continue
+		}
+		my.time.flushTo(item.unix, item.nsec)
+		my.encode(buf, item)
+	}
+	my.flush(my.writer, buf, len(*buf))
 }
 
 func NewLogStdWriter(writer io.Writer) *logStdWriter {
@@ -100,10 +278,20 @@ func NewLogStdWriter(writer io.Writer) *logStdWriter {
 	return out
 }
 
+const (
+	LOG_COMPRESS_NONE = iota
+	LOG_COMPRESS_GZIP
+)
+
+func newGzipWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
 type logFileWriter struct {
 	logBaseWriter
 	writer            *handleFile
 	filePathFormatter string
+	janitor           *fileJanitor
 	mu                sync.Mutex
 }
 
@@ -111,56 +299,260 @@ func (my *logFileWriter) NextPathName() (folderPath string, fileName string) {
 	return filepath.Split(my.time.format(my.filePathFormatter))
 }
 
-func (my *logFileWriter) Write(unix int64, level int, file string, line int, content string) {
+func (my *logFileWriter) Write(item *Entry) {
+	if !my.accept(item) {
+		return
+	}
 	my.mu.Lock()
 	defer my.mu.Unlock()
-	my.time.flushTo(unix)
+	my.time.flushTo(item.unix, item.nsec)
 	my.writer.SetPathName(my.NextPathName())
-	my.write(my.writer, level, file, line, content)
+	my.write(my.writer, item)
+}
+
+//编码到同一个缓冲区后一次写入, 切换文件或将超过单个文件大小时先写入已编码部分
+func (my *logFileWriter) WriteBatch(items []*Entry) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	buf := getBuffer(itemsSize(items))
+	defer buf.free()
+	for _, item := range items {
+		if !my.accept(item) {
+			continue
+		}
+		my.time.flushTo(item.unix, item.nsec)
+		folderPath, fileName := my.NextPathName()
+		if folderPath != my.writer.folderPath || fileName != my.writer.fileName {
+			my.flush(my.writer, buf, len(*buf))
+			my.writer.SetPathName(folderPath, fileName)
+		}
+		n := len(*buf)
+		my.encode(buf, item)
+		if n > 0 && my.writer.maxSize > 0 && my.writer.size+int64(len(*buf)) > my.writer.maxSize {
+			my.flush(my.writer, buf, n)
+		}
+	}
+	my.flush(my.writer, buf, len(*buf))
+}
+
+//按大小滚动: 同一时间段内超过 maxSize 字节时依次写入 .1 .2 ... 后缀文件, 0不限制
+func (my *logFileWriter) SetMaxSize(maxSize int64) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.writer.SetMaxSize(maxSize)
+}
+
+//保留策略: 超过 maxAge, 超出 maxFiles 个或总大小超过 maxBytes 的旧文件由后台协程删除, 0不限制
+//只清理当前文件所在目录中与路径格式文件名部分匹配的文件
+func (my *logFileWriter) SetRetention(maxAge time.Duration, maxFiles int, maxBytes int64) {
+	my.janitor.SetRetention(retention{maxAge: maxAge, maxFiles: maxFiles, maxBytes: maxBytes})
+}
+
+//LOG_COMPRESS_NONE 或 LOG_COMPRESS_GZIP, 切换到新文件后在后台压缩上一个文件
+func (my *logFileWriter) SetCompress(compress int) {
+	switch compress {
+	case LOG_COMPRESS_NONE:
+		my.SetCompressor("", nil)
+	case LOG_COMPRESS_GZIP:
+		my.SetCompressor(".gz", newGzipWriter)
+	}
+}
+
+//自定义压缩算法, 如 zstd(标准库未提供): SetCompressor(".zst", func(w io.Writer) (io.WriteCloser, error) {...})
+func (my *logFileWriter) SetCompressor(ext string, compressor func(w io.Writer) (io.WriteCloser, error)) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	if compressor == nil {
+		ext = ""
+	}
+	my.writer.compressExt = ext
+	my.janitor.SetCompressor(ext, compressor)
+}
+
+//设置保留策略计算文件时长使用的时钟, nil 为系统时钟; 文件切换由日志时间决定
+func (my *logFileWriter) SetClock(clock Clock) {
+	my.janitor.SetClock(clock)
+}
+
+func (my *logFileWriter) switched(prevPath, path string) {
+	my.janitor.Notify(prevPath, path)
 }
 
 func (my *logFileWriter) Close() {
 	my.mu.Lock()
 	defer my.mu.Unlock()
 	my.writer.Close()
+	my.janitor.Close()
 }
 
 func NewLogFileWriter(filePathFormatter string) *logFileWriter {
 	out := &logFileWriter{}
-	out.writer = &handleFile{flag: os.O_WRONLY | os.O_APPEND | os.O_CREATE, perm: 0777}
+	out.writer = &handleFile{flag: os.O_WRONLY | os.O_APPEND | os.O_CREATE, perm: 0777, onSwitch: out.switched}
 	if filePathFormatter == "" {
 		out.filePathFormatter = filepath.Join(execDir(), "output.log.%Y-%m-%d-%H")
 	} else {
 		out.filePathFormatter = filePathFormatter
 	}
+	out.janitor = newFileJanitor(out.filePathFormatter, out.reportError)
 	return out
 }
 
-type stdLogger struct {
+//同一 logger 及其派生 logger 共享的 handler, 队列等
+type loggerCore struct {
+	dropped  uint64 //队列满时丢弃的日志数
 	level    int
+	vmodule  atomic.Value //*vmodule
 	handlers []logWriter
 	queue    *queue
+	capacity int
+	overflow int32        //LOG_OVERFLOW_*, 每次写入队列时读取, 原子操作
+	batch    atomic.Value //batchConfig, 异步写入协程运行时也可修改
+	clock    Clock
 	running  int32
 	async    bool
 	wg       sync.WaitGroup
 }
 
-func (my *stdLogger) output(level int, content string) {
-	if level < my.level {
-		return
+type stdLogger struct {
+	*loggerCore
+	skip   int     //额外跳过的调用层数
+	name   string  //名称, 子 logger 以 . 连接
+	fields []Field //每条日志附带的字段
+}
+
+//返回附带字段的子 logger, 与父 logger 共用等级、handler 及队列
+func (my *stdLogger) With(keysAndValues ...interface{}) *stdLogger {
+	child := *my
+	fields := toFields(keysAndValues)
+	if len(fields) > 0 {
+		child.fields = make([]Field, 0, len(my.fields)+len(fields))
+		child.fields = append(append(child.fields, my.fields...), fields...)
 	}
-	file, line := callerShort(logSkip)
-	my.write(unix(), level, file, line, content)
+	return &child
+}
+
+//返回指定名称的子 logger, 名称以 . 连接在父 logger 名称之后, 如 billing.db
+func (my *stdLogger) Named(name string) *stdLogger {
+	child := *my
+	if my.name == "" {
+		child.name = name
+	} else if name != "" {
+		child.name = my.name + "." + name
+	}
+	return &child
+}
+
+//返回额外跳过 skip 层调用的 logger, 用于封装日志函数时输出真实的调用位置
+func (my *stdLogger) WithCallerSkip(skip int) *stdLogger {
+	child := *my
+	child.skip += skip
+	return &child
+}
+
+//设置时钟, nil 为系统时钟; 日志时间取自该时钟, 文件按日志时间切换
+func (my *loggerCore) SetClock(clock Clock) {
+	my.clock = clock
 }
 
-func (my *stdLogger) SetLevel(level int) {
-	if level < LOG_LEVEL_DEBUG || level > LOG_LEVEL_OFF {
+func (my *loggerCore) now() (sec int64, nsec int32) {
+	if my.clock == nil {
+		return now()
+	}
+	t := my.clock.Now()
+	return t.Unix(), int32(t.Nanosecond())
+}
+
+//是否可能输出该等级的日志; 设置了 vmodule 时各调用位置的等级不同, 输出时再按调用位置判断
+func (my *loggerCore) Enabled(level int) bool {
+	if level >= my.level {
+		return true
+	}
+	vm := my.getVModule()
+	return vm != nil && level >= vm.minLevel
+}
+
+//按调用位置判断是否输出, 匹配 vmodule 规则时使用规则的等级
+func (my *loggerCore) enabledAt(level int, pc uintptr) bool {
+	if vm := my.getVModule(); vm != nil {
+		if ruleLevel := vm.levelAt(pc); ruleLevel != vmoduleNoRule {
+			return level >= ruleLevel
+		}
+	}
+	return level >= my.level
+}
+
+func (my *loggerCore) getVModule() *vmodule {
+	vm, _ := my.vmodule.Load().(*vmodule)
+	return vm
+}
+
+//按包或文件设置等级, 可在运行时修改, 如 "db/*=debug,http/handler.go=trace,*=info";
+//以 .go 结尾的规则匹配文件路径末尾, 其它匹配包路径末尾或 "包路径/文件名" 末尾, 支持 path.Match 通配符;
+//如 db/* 匹配包 db 中的文件及其直接子包 db/mysql, db 只匹配包 db 本身;
+//按顺序第一个匹配的规则生效, 没有匹配时使用 SetLevel 的等级; 空字符串清除规则
+func (my *loggerCore) SetVModule(spec string) error {
+	vm, err := parseVModule(spec)
+	if err != nil {
+		return err
+	}
+	my.vmodule.Store(vm)
+	return nil
+}
+
+func (my *stdLogger) output(level int, content string, fields []Field) {
+	my.outputAt(level, callerHelperPC(logSkip-1+my.skip), time.Time{}, content, fields)
+}
+
+//指定调用位置及时间输出, t 为零值时使用 logger 的时钟
+func (my *stdLogger) outputAt(level int, pc uintptr, t time.Time, content string, fields []Field) {
+	if !my.enabledAt(level, pc) {
 		return
 	}
+	item := entryFree.Get().(*Entry)
+	item.frame = frameOf(pc)
+	if t.IsZero() {
+		item.unix, item.nsec = my.now()
+	} else {
+		item.unix, item.nsec = t.Unix(), int32(t.Nanosecond())
+	}
+	item.level = level
+	item.name = my.name
+	item.content = content
+	item.fields = fields
+	if len(my.fields) > 0 {
+		item.fields = my.fields
+		if len(fields) > 0 {
+			item.fields = make([]Field, 0, len(my.fields)+len(fields))
+			item.fields = append(append(item.fields, my.fields...), fields...)
+		}
+	}
+	if my.needGoroutine() {
+		item.goid = goroutineID()
+	}
+	my.write(item)
+}
+
+//有 handler 的布局包含 %gid 时才获取协程ID
+func (my *loggerCore) needGoroutine() bool {
+	for _, handler := range my.handlers {
+		if capture, ok := handler.(interface{ needGoroutine() bool }); ok && capture.needGoroutine() {
+			return true
+		}
+	}
+	return false
+}
+
+//兼容旧版本的等级数值 0-5, 其它超出范围的等级返回错误且不修改
+func (my *loggerCore) SetLevel(level int) error {
+	level, err := normalizeLevel(level)
+	if err != nil {
+		return err
+	}
 	my.level = level
+	return nil
 }
 
-func (my *stdLogger) AddHandler(handlers ... logWriter) {
+func (my *loggerCore) AddHandler(handlers ...logWriter) {
 	for _, handler := range handlers {
 		if handler == nil {
 			continue
@@ -169,7 +561,7 @@ func (my *stdLogger) AddHandler(handlers ... logWriter) {
 	}
 }
 
-func (my *stdLogger) SetAsync(async bool) {
+func (my *loggerCore) SetAsync(async bool) {
 	my.async = async
 	if async {
 		my.start()
@@ -178,18 +570,59 @@ func (my *stdLogger) SetAsync(async bool) {
 	}
 }
 
-func (my *stdLogger) start() {
+//异步队列容量及队列满时的处理方式(LOG_OVERFLOW_*), capacity 为0时不限制
+func (my *loggerCore) SetQueueCapacity(capacity int, overflow int) {
+	if overflow < LOG_OVERFLOW_BLOCK || overflow > LOG_OVERFLOW_SYNC {
+		return
+	}
+	my.capacity = capacity
+	atomic.StoreInt32(&my.overflow, int32(overflow))
+	if my.queue != nil {
+		my.queue.SetCapacity(capacity)
+	}
+}
+
+//异步模式下每批最多写入 maxSize 条, 不足一批时最多等待 maxLatency 后写入
+func (my *loggerCore) SetBatch(maxSize int, maxLatency time.Duration) {
+	if maxSize < 1 {
+		maxSize = 1
+	}
+	if maxLatency < 0 {
+		maxLatency = 0
+	}
+	my.batch.Store(batchConfig{size: maxSize, latency: maxLatency})
+}
+
+type batchConfig struct {
+	size    int
+	latency time.Duration
+}
+
+func (my *loggerCore) batchConfig() batchConfig {
+	if config, ok := my.batch.Load().(batchConfig); ok {
+		return config
+	}
+	return batchConfig{size: defaultBatchSize}
+}
+
+//队列满时丢弃的日志数
+func (my *loggerCore) Dropped() uint64 {
+	return atomic.LoadUint64(&my.dropped)
+}
+
+func (my *loggerCore) start() {
 	if atomic.CompareAndSwapInt32(&my.running, 0, 1) {
 		if my.queue == nil {
 			my.queue = newQueue()
 		}
+		my.queue.SetCapacity(my.capacity)
 		my.queue.Open()
 		my.wg.Add(1)
 		go my.run()
 	}
 }
 
-func (my *stdLogger) Wait() {
+func (my *loggerCore) Wait() {
 	if atomic.CompareAndSwapInt32(&my.running, 1, 0) {
 		my.queue.Close()
 		my.wg.Wait()
@@ -197,88 +630,390 @@ func (my *stdLogger) Wait() {
 	my.close()
 }
 
-func (my *stdLogger) SetFormatHeader(formatHeader func(buf *Buffer, level string, line int, file string, dt DateTime)) {
+func (my *loggerCore) SetFormatHeader(formatHeader func(buf *Buffer, level string, line int, file string, dt DateTime)) {
+	for _, handler := range my.handlers {
+		if setter, ok := handler.(interface {
+			SetFormatHeader(formatHeader func(buf *Buffer, level string, line int, file string, dt DateTime))
+		}); ok {
+			setter.SetFormatHeader(formatHeader)
+		}
+	}
+}
+
+//设置所有 handler 的布局, 见 logBaseWriter.SetLayout
+func (my *loggerCore) SetLayout(layout string) error {
+	if _, err := compileLayout(layout); err != nil {
+		return err
+	}
+	for _, handler := range my.handlers {
+		if setter, ok := handler.(interface{ SetLayout(layout string) error }); ok {
+			setter.SetLayout(layout)
+		}
+	}
+	return nil
+}
+
+func (my *loggerCore) SetColor(color bool) {
+	for _, handler := range my.handlers {
+		if setter, ok := handler.(interface{ SetColor(color bool) }); ok {
+			setter.SetColor(color)
+		}
+	}
+}
+
+func (my *loggerCore) SetCallerMode(mode int) {
 	for _, handler := range my.handlers {
-		handler.SetFormatHeader(formatHeader)
+		if setter, ok := handler.(interface{ SetCallerMode(mode int) }); ok {
+			setter.SetCallerMode(mode)
+		}
+	}
+}
+
+func (my *loggerCore) SetFormat(format int) {
+	for _, handler := range my.handlers {
+		if setter, ok := handler.(interface{ SetFormat(format int) }); ok {
+			setter.SetFormat(format)
+		}
+	}
+}
+
+func (my *loggerCore) SetLocation(loc *time.Location) {
+	for _, handler := range my.handlers {
+		if setter, ok := handler.(interface{ SetLocation(loc *time.Location) }); ok {
+			setter.SetLocation(loc)
+		}
 	}
 }
 
-func (my *stdLogger) close() {
+func (my *loggerCore) close() {
 	for _, handler := range my.handlers {
 		handler.Close()
 	}
 }
 
-func (my *stdLogger) run() {
+func (my *loggerCore) run() {
 	defer Exception(func(stack string, e error) {
 		fmt.Println(stack, e)
 	})
 	defer my.wg.Done()
-	var item *logItem
-	var ok bool
-	var handler logWriter
-	var value interface{}
+	var batch []interface{}
+	var items []*Entry
 	var closed bool
 	for {
-		value, closed = my.queue.PopBlock()
+		config := my.batchConfig()
+		batch, closed = my.queue.PopBatch(batch[:0], config.size, config.latency)
 		if closed {
 			break
 		}
-		item, ok = value.(*logItem)
-		if !ok {
-			break
+		items = items[:0]
+		for i, value := range batch {
+			switch x := value.(type) {
+			case *Entry:
+				items = append(items, x)
+			case flushMarker:
+				items = my.dispatch(items)
+				close(x)
+			}
+			batch[i] = nil
+		}
+		items = my.dispatch(items)
+	}
+}
+
+//写入所有 handler 后释放, 返回清空后的 items
+func (my *loggerCore) dispatch(items []*Entry) []*Entry {
+	if len(items) == 0 {
+		return items
+	}
+	for _, handler := range my.handlers {
+		if batchWriter, ok := handler.(logBatchWriter); ok {
+			batchWriter.WriteBatch(items)
+			continue
 		}
-		for _, handler = range my.handlers {
-			handler.Write(item.unix, item.level, item.file, item.line, item.content)
+		for _, item := range items {
+			handler.Write(item)
 		}
+	}
+	for i, item := range items {
 		item.free()
+		items[i] = nil
+	}
+	return items[:0]
+}
+
+//异步队列中的标记, 写入到该位置时关闭
+type flushMarker chan struct{}
+
+func isFlushMarker(v interface{}) bool {
+	_, ok := v.(flushMarker)
+	return ok
+}
+
+//等待异步队列中已有的日志写入完成, 不停止异步写入
+func (my *loggerCore) Flush() {
+	if atomic.LoadInt32(&my.running) == 0 {
+		return
+	}
+	marker := make(flushMarker)
+	if my.queue.Push(marker) {
+		<-marker
 	}
 }
 
-func (my *stdLogger) write(unix int64, level int, file string, line int, content string) () {
+func (my *loggerCore) write(item *Entry) {
 	if my.async {
-		item := logItemFree.Get().(*logItem)
-		item.unix = unix
-		item.level = level
-		item.content = content
-		item.file = file
-		item.line = line
-		if my.queue.Push(item) {
+		if my.push(item) {
 			return
 		}
 	}
 	for _, handler := range my.handlers {
-		handler.Write(unix, level, file, line, content)
+		handler.Write(item)
+	}
+	item.free()
+}
+
+//写入异步队列, 返回false时由调用者同步写入
+func (my *loggerCore) push(item *Entry) bool {
+	switch atomic.LoadInt32(&my.overflow) {
+	case LOG_OVERFLOW_DROP_NEWEST:
+		pushed, full := my.queue.TryPush(item)
+		if full {
+			atomic.AddUint64(&my.dropped, 1)
+			item.free()
+			return true
+		}
+		return pushed
+	case LOG_OVERFLOW_DROP_OLDEST:
+		//不移除 Flush 标记, 否则 Flush 可能在之前的日志写入前返回
+		evicted, pushed := my.queue.PushEvict(item, isFlushMarker)
+		if x, ok := evicted.(*Entry); ok {
+			atomic.AddUint64(&my.dropped, 1)
+			x.free()
+		}
+		return pushed
+	case LOG_OVERFLOW_SYNC:
+		pushed, _ := my.queue.TryPush(item)
+		return pushed
+	default:
+		return my.queue.PushWait(item)
+	}
+}
+
+//按指定等级输出, 用于自定义等级
+func (my *stdLogger) Log(level int, v ...interface{}) {
+	if my.Enabled(level) {
+		my.output(level, fmt.Sprint(lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) LogF(level int, format string, v ...interface{}) {
+	if my.Enabled(level) {
+		my.output(level, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) LogW(level int, msg string, keysAndValues ...interface{}) {
+	if my.Enabled(level) {
+		my.output(level, msg, toFields(keysAndValues))
 	}
 }
 
-func (my *stdLogger) Debug(v ...interface{})                 { my.output(LOG_LEVEL_DEBUG, fmt.Sprint(v...)) }
-func (my *stdLogger) Info(v ...interface{})                  { my.output(LOG_LEVEL_INFO, fmt.Sprint(v...)) }
-func (my *stdLogger) Warn(v ...interface{})                  { my.output(LOG_LEVEL_WARN, fmt.Sprint(v...)) }
-func (my *stdLogger) Error(v ...interface{})                 { my.output(LOG_LEVEL_ERROR, fmt.Sprint(v...)) }
-func (my *stdLogger) Fatal(v ...interface{})                 { my.output(LOG_LEVEL_FATAL, fmt.Sprint(v...)) }
-func (my *stdLogger) DebugF(format string, v ...interface{}) { my.output(LOG_LEVEL_DEBUG, fmt.Sprintf(format, v...)) }
-func (my *stdLogger) InfoF(format string, v ...interface{})  { my.output(LOG_LEVEL_INFO, fmt.Sprintf(format, v...)) }
-func (my *stdLogger) WarnF(format string, v ...interface{})  { my.output(LOG_LEVEL_WARN, fmt.Sprintf(format, v...)) }
-func (my *stdLogger) ErrorF(format string, v ...interface{}) { my.output(LOG_LEVEL_ERROR, fmt.Sprintf(format, v...)) }
-func (my *stdLogger) FatalF(format string, v ...interface{}) { my.output(LOG_LEVEL_FATAL, fmt.Sprintf(format, v...)) }
+func (my *stdLogger) Trace(v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_TRACE) {
+		my.output(LOG_LEVEL_TRACE, fmt.Sprint(lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) Debug(v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_DEBUG) {
+		my.output(LOG_LEVEL_DEBUG, fmt.Sprint(lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) Info(v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_INFO) {
+		my.output(LOG_LEVEL_INFO, fmt.Sprint(lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) Warn(v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_WARN) {
+		my.output(LOG_LEVEL_WARN, fmt.Sprint(lazyArgs(v)...), nil)
+	}
+}
 
-func NewLogger(level int, async bool, handlers ... logWriter) *stdLogger {
-	logger := &stdLogger{}
+func (my *stdLogger) Error(v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_ERROR) {
+		my.output(LOG_LEVEL_ERROR, fmt.Sprint(lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) Fatal(v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_FATAL) {
+		my.output(LOG_LEVEL_FATAL, fmt.Sprint(lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) TraceF(format string, v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_TRACE) {
+		my.output(LOG_LEVEL_TRACE, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) DebugF(format string, v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_DEBUG) {
+		my.output(LOG_LEVEL_DEBUG, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) InfoF(format string, v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_INFO) {
+		my.output(LOG_LEVEL_INFO, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) WarnF(format string, v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_WARN) {
+		my.output(LOG_LEVEL_WARN, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) ErrorF(format string, v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_ERROR) {
+		my.output(LOG_LEVEL_ERROR, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) FatalF(format string, v ...interface{}) {
+	if my.Enabled(LOG_LEVEL_FATAL) {
+		my.output(LOG_LEVEL_FATAL, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	}
+}
+
+func (my *stdLogger) TraceW(msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_TRACE) {
+		my.output(LOG_LEVEL_TRACE, msg, toFields(keysAndValues))
+	}
+}
+
+//结构化日志: msg 后跟 key/value 交替参数或 Field
+func (my *stdLogger) DebugW(msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_DEBUG) {
+		my.output(LOG_LEVEL_DEBUG, msg, toFields(keysAndValues))
+	}
+}
+
+func (my *stdLogger) InfoW(msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_INFO) {
+		my.output(LOG_LEVEL_INFO, msg, toFields(keysAndValues))
+	}
+}
+
+func (my *stdLogger) WarnW(msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_WARN) {
+		my.output(LOG_LEVEL_WARN, msg, toFields(keysAndValues))
+	}
+}
+
+func (my *stdLogger) ErrorW(msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_ERROR) {
+		my.output(LOG_LEVEL_ERROR, msg, toFields(keysAndValues))
+	}
+}
+
+func (my *stdLogger) FatalW(msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_FATAL) {
+		my.output(LOG_LEVEL_FATAL, msg, toFields(keysAndValues))
+	}
+}
+
+//按 context 输出, 附带 RegisterContextExtractor 从 ctx 中提取的字段, 如 trace_id, request_id
+func (my *stdLogger) LogCtx(ctx context.Context, level int, msg string, keysAndValues ...interface{}) {
+	if my.Enabled(level) {
+		my.output(level, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func (my *stdLogger) TraceCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_TRACE) {
+		my.output(LOG_LEVEL_TRACE, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func (my *stdLogger) DebugCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_DEBUG) {
+		my.output(LOG_LEVEL_DEBUG, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func (my *stdLogger) InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_INFO) {
+		my.output(LOG_LEVEL_INFO, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func (my *stdLogger) WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_WARN) {
+		my.output(LOG_LEVEL_WARN, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func (my *stdLogger) ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_ERROR) {
+		my.output(LOG_LEVEL_ERROR, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+func (my *stdLogger) FatalCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if my.Enabled(LOG_LEVEL_FATAL) {
+		my.output(LOG_LEVEL_FATAL, msg, withContextFields(ctx, toFields(keysAndValues)))
+	}
+}
+
+//输出 FATAL 日志并等待写入后 panic
+func (my *stdLogger) Panic(v ...interface{}) {
+	content := fmt.Sprint(lazyArgs(v)...)
+	my.output(LOG_LEVEL_FATAL, content, nil)
+	my.Flush()
+	panic(content)
+}
+
+func (my *stdLogger) PanicF(format string, v ...interface{}) {
+	content := fmt.Sprintf(format, lazyArgs(v)...)
+	my.output(LOG_LEVEL_FATAL, content, nil)
+	my.Flush()
+	panic(content)
+}
+
+//输出 FATAL 日志, 等待所有异步日志写入后退出进程
+func (my *stdLogger) Exit(v ...interface{}) {
+	my.output(LOG_LEVEL_FATAL, fmt.Sprint(lazyArgs(v)...), nil)
+	Wait()
+	os.Exit(1)
+}
+
+func (my *stdLogger) ExitF(format string, v ...interface{}) {
+	my.output(LOG_LEVEL_FATAL, fmt.Sprintf(format, lazyArgs(v)...), nil)
+	Wait()
+	os.Exit(1)
+}
+
+func NewLogger(level int, async bool, handlers ...logWriter) *stdLogger {
+	logger := &stdLogger{loggerCore: &loggerCore{}}
 	logger.SetLevel(level)
 	logger.AddHandler(handlers...)
 	logger.SetAsync(async)
-	runtime.SetFinalizer(logger, (*stdLogger).Wait)
-	loggerMgr.Append(logger)
+	runtime.SetFinalizer(logger.loggerCore, (*loggerCore).Wait)
+	loggerMgr.Append(logger.loggerCore)
 	return logger
 }
 
 type loggerManager struct {
-	loggers []*stdLogger
+	loggers []*loggerCore
 	mu      sync.Mutex
 }
 
-func (my *loggerManager) Append(logger *stdLogger) {
+func (my *loggerManager) Append(logger *loggerCore) {
 	my.mu.Lock()
 	defer my.mu.Unlock()
 	my.loggers = append(my.loggers, logger)
@@ -290,25 +1025,86 @@ func (my *loggerManager) Wait() {
 	}
 }
 
-type logItem struct {
+//参数中的 func() string 在确定输出时才求值
+func lazyArgs(v []interface{}) []interface{} {
+	for i := range v {
+		if _, ok := v[i].(func() string); !ok {
+			continue
+		}
+		args := make([]interface{}, len(v))
+		copy(args, v)
+		for j := i; j < len(args); j++ {
+			if lazy, ok := args[j].(func() string); ok {
+				args[j] = lazy()
+			}
+		}
+		return args
+	}
+	return v
+}
+
+//一条日志, 由 logger 传给各 writer
+type Entry struct {
 	unix    int64
+	nsec    int32
 	level   int
+	name    string //logger 名称
 	content string
-	file    string
-	line    int
+	frame   *callerFrame
+	goid    int64
+	fields  []Field
+}
+
+func (it *Entry) Time() time.Time {
+	return time.Unix(it.unix, int64(it.nsec))
+}
+
+//LOG_LEVEL_* 或 RegisterLevel 注册的等级
+func (it *Entry) Level() int {
+	return it.level
+}
+
+//logger 名称, 未命名时为空
+func (it *Entry) Name() string {
+	return it.name
+}
+
+func (it *Entry) Message() string {
+	return it.content
+}
+
+//结构化字段, 不能修改
+func (it *Entry) Fields() []Field {
+	return it.fields
+}
+
+//调用位置的完整路径
+func (it *Entry) File() string {
+	return it.frame.path
+}
+
+func (it *Entry) Line() int {
+	return it.frame.line
+}
+
+//调用位置的完整函数名, 如 github.com/user/project/api.(*Server).Handle
+func (it *Entry) Function() string {
+	return it.frame.function
 }
 
-func (it *logItem) free() {
-	logItemFree.Put(it)
+func (it *Entry) free() {
+	it.fields = nil
+	it.goid = 0
+	entryFree.Put(it)
 }
 
 var loggerMgr loggerManager
 
-var logItemFree = sync.Pool{New: func() interface{} { return new(logItem) },}
+var entryFree = sync.Pool{New: func() interface{} { return new(Entry) }}
 
-var log = stdLogger{level: LOG_LEVEL_DEBUG, handlers: []logWriter{NewLogStdWriter(os.Stdout)}}
+var log = stdLogger{loggerCore: &loggerCore{level: LOG_LEVEL_DEBUG, handlers: []logWriter{NewLogStdWriter(os.Stdout)}}}
 
 func init() {
-	runtime.SetFinalizer(&log, (*stdLogger).Wait)
+	runtime.SetFinalizer(log.loggerCore, (*loggerCore).Wait)
 	log.SetAsync(true)
 }
@@ -0,0 +1,194 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (my *syncBuffer) Write(p []byte) (int, error) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	return my.buf.Write(p)
+}
+
+func (my *syncBuffer) String() string {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	return my.buf.String()
+}
+
+//异步写入时修改批量设置, go test -race 检查
+func TestSetBatchWhileRunning(t *testing.T) {
+	out := &syncBuffer{}
+	logger := NewLogger(LOG_LEVEL_DEBUG, true, NewLogStdWriter(out))
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			logger.Info("line")
+		}
+	}()
+	for i := 1; i <= 10; i++ {
+		logger.SetBatch(i, time.Duration(i)*time.Microsecond)
+	}
+	wg.Wait()
+	logger.Wait()
+	if n := strings.Count(out.String(), "\n"); n != 100 {
+		t.Fatalf("got %d lines, want 100", n)
+	}
+}
+
+//批量写入时阻塞到 gate 关闭, 以便填满异步队列; 同步写入不阻塞
+type gateWriter struct {
+	gate    chan struct{}
+	batched int64
+	synced  int64
+}
+
+func (my *gateWriter) Write(entry *Entry) {
+	atomic.AddInt64(&my.synced, 1)
+}
+
+func (my *gateWriter) WriteBatch(entries []*Entry) {
+	<-my.gate
+	atomic.AddInt64(&my.batched, int64(len(entries)))
+}
+
+func (my *gateWriter) Close() {}
+
+//队列满时按各策略处理, 写入数与丢弃数之和等于日志数
+func TestQueueOverflow(t *testing.T) {
+	const logged = 100
+	tests := []struct {
+		name     string
+		overflow int
+		dropped  bool
+	}{
+		{"block", LOG_OVERFLOW_BLOCK, false},
+		{"drop newest", LOG_OVERFLOW_DROP_NEWEST, true},
+		{"drop oldest", LOG_OVERFLOW_DROP_OLDEST, true},
+		{"sync", LOG_OVERFLOW_SYNC, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			writer := &gateWriter{gate: make(chan struct{})}
+			logger := NewLogger(LOG_LEVEL_DEBUG, false, writer)
+			logger.SetQueueCapacity(10, test.overflow)
+			logger.SetAsync(true)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < logged; i++ {
+					logger.Info("line")
+				}
+			}()
+			if test.overflow == LOG_OVERFLOW_BLOCK {
+				select {
+				case <-done:
+					t.Fatal("caller not blocked by a full queue")
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+			close(writer.gate)
+			<-done
+			logger.Wait()
+			written := uint64(writer.batched + writer.synced)
+			if written+logger.Dropped() != logged {
+				t.Fatalf("written %d + dropped %d != %d", written, logger.Dropped(), logged)
+			}
+			if (logger.Dropped() > 0) != test.dropped {
+				t.Fatalf("dropped %d", logger.Dropped())
+			}
+			if (writer.synced > 0) != (test.overflow == LOG_OVERFLOW_SYNC) {
+				t.Fatalf("synchronously written %d", writer.synced)
+			}
+		})
+	}
+}
+
+//写入时修改队列容量及策略, go test -race 检查
+func TestSetQueueCapacityWhileRunning(t *testing.T) {
+	out := &syncBuffer{}
+	logger := NewLogger(LOG_LEVEL_DEBUG, true, NewLogStdWriter(out))
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			logger.Info("line")
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		logger.SetQueueCapacity(1000, i%2*LOG_OVERFLOW_SYNC)
+	}
+	wg.Wait()
+	logger.Wait()
+	if n := strings.Count(out.String(), "\n"); n != 100 {
+		t.Fatalf("got %d lines, want 100", n)
+	}
+}
+
+//默认头部与旧版本相同, 不含小数秒
+func TestDefaultHeader(t *testing.T) {
+	out := &syncBuffer{}
+	logger := NewLogger(LOG_LEVEL_DEBUG, false, NewLogStdWriter(out))
+	logger.SetLocation(time.UTC)
+	logger.SetClock(NewManualClock(time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)))
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("hello")
+	logger.Wait()
+	if want := fmt.Sprintf("[INFO 2024/05/06 07:08:09 logger_test.go:%d] hello\n", line+1); out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+}
+
+//LOG_OVERFLOW_DROP_OLDEST 时 Flush 标记不被移除, Flush 等待正在写入的日志
+func TestFlushNotEvicted(t *testing.T) {
+	writer := &gateWriter{gate: make(chan struct{})}
+	logger := NewLogger(LOG_LEVEL_DEBUG, false, writer)
+	logger.SetQueueCapacity(1, LOG_OVERFLOW_DROP_OLDEST)
+	logger.SetAsync(true)
+	logger.Fatal("fatal")
+	//等待 fatal 被异步写入协程取出, 写入时阻塞
+	for {
+		logger.queue.mutex.Lock()
+		n := logger.queue.len
+		logger.queue.mutex.Unlock()
+		if n == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	flushed := make(chan struct{})
+	go func() {
+		logger.Flush()
+		close(flushed)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		logger.Info("line")
+	}
+	select {
+	case <-flushed:
+		t.Fatal("Flush returned before the fatal entry was written")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(writer.gate)
+	<-flushed
+	if atomic.LoadInt64(&writer.batched) < 1 {
+		t.Fatal("fatal entry not written")
+	}
+	logger.Wait()
+}
@@ -33,7 +33,10 @@ type queue struct {
 	first *queueNode
 	last *queueNode
 	mutex sync.Mutex
+	notFull *sync.Cond
+	notify chan struct{} //唤醒 PopBlock 中等待的消费者
 	len int
+	capacity int //最大长度, 0不限制
 	closed bool
 }
 
@@ -42,10 +45,28 @@ func newQueue() *queue {
 	n := newQueueNode()
 	q.first = n
 	q.last = n
+	q.notFull = sync.NewCond(&q.mutex)
+	q.notify = make(chan struct{}, 1)
 	return q
 }
 
+func (q *queue)SetCapacity(capacity int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if capacity < 0{
+		capacity = 0
+	}
+	q.capacity = capacity
+	q.notFull.Broadcast()
+}
+
+func (q *queue)full() bool {
+	return q.capacity > 0 && q.len >= q.capacity
+}
+
 func (q *queue)Closed() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
 	return q.closed
 }
 
@@ -55,6 +76,15 @@ func (q *queue)Close() {
 	if q.closed == false{
 		q.closed = true
 	}
+	q.notFull.Broadcast()
+	q.wakeup()
+}
+
+func (q *queue)wakeup() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
 }
 
 func (q *queue)Open() {
@@ -69,6 +99,9 @@ func (q *queue)push(v interface{}) {
 	q.last.data[q.last.end] = v
 	q.last.end++
 	q.len++
+	if q.len == 1{
+		q.wakeup()
+	}
 	if q.last.end == q.last.size{
 		n := newQueueNode()
 		q.last.next = n
@@ -86,26 +119,96 @@ func (q *queue)Push(v interface{}) bool {
 	return true
 }
 
-func (q *queue)PushForce(v interface{}) {
+//队列已满时阻塞等待, 关闭时返回false
+func (q *queue)PushWait(v interface{}) bool {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
+	for q.full() && q.closed == false{
+		q.notFull.Wait()
+	}
+	if q.closed == true{
+		return false
+	}
 	q.push(v)
+	return true
 }
 
-func (q *queue)Pop() (v interface{}, closed bool) {
+//队列已满时不写入, full=true
+func (q *queue)TryPush(v interface{}) (pushed bool, full bool) {
 	q.mutex.Lock()
-	closed = q.closed
+	defer q.mutex.Unlock()
+	if q.closed == true{
+		return false, false
+	}
+	if q.full(){
+		return false, true
+	}
+	q.push(v)
+	return true, false
+}
+
+//队列已满时移除最早的元素后写入, 返回被移除的元素
+//keep 返回 true 的元素(如 Flush 标记)不被移除, 保持原有顺序; 没有可移除的元素时直接写入
+func (q *queue)PushEvict(v interface{}, keep func(v interface{}) bool) (evicted interface{}, pushed bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed == true{
+		return nil, false
+	}
+	if q.full(){
+		var kept []interface{}
+		for {
+			x, ok := q.pop()
+			if !ok{
+				break
+			}
+			if keep == nil || !keep(x){
+				evicted = x
+				break
+			}
+			kept = append(kept, x)
+		}
+		for i := len(kept) - 1; i >= 0; i--{
+			q.pushFront(kept[i])
+		}
+	}
+	q.push(v)
+	return evicted, true
+}
+
+//放回队列头部, 用于恢复 PushEvict 取出的元素
+func (q *queue)pushFront(v interface{}) {
+	if q.first.pos == 0{
+		n := newQueueNode()
+		n.pos = n.size
+		n.end = n.size
+		n.next = q.first
+		q.first = n
+	}
+	q.first.pos--
+	q.first.data[q.first.pos] = v
+	q.len++
+}
+
+func (q *queue)PushForce(v interface{}) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.push(v)
+}
+
+func (q *queue)pop() (v interface{}, ok bool) {
 	for{
 		if q.first.pos < q.first.end{
 			v = q.first.data[q.first.pos]
+			q.first.data[q.first.pos] = nil
 			q.first.pos++
 			q.len--
-			q.mutex.Unlock()
-			closed = false
-			return
+			if q.capacity > 0{
+				q.notFull.Signal()
+			}
+			return v, true
 		} else if q.first.pos == q.first.size{
 			if q.first.next == nil{
-				q.mutex.Unlock()
 				return
 			}
 			first := q.first
@@ -113,45 +216,75 @@ func (q *queue)Pop() (v interface{}, closed bool) {
 			first.free()
 			continue
 		}
-		q.mutex.Unlock()
 		return
 	}
 }
 
-func (q *queue)PopBlock() (v interface{}, closed bool) {
+func (q *queue)Pop() (v interface{}, closed bool) {
 	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var ok bool
+	if v, ok = q.pop(); ok{
+		return v, false
+	}
+	return nil, q.closed
+}
+
+//队列为空时等待 Push 唤醒, 关闭且为空时返回 closed=true
+func (q *queue)PopBlock() (v interface{}, closed bool) {
+	var ok bool
 	for {
-		if q.first.pos < q.first.end{
-			v = q.first.data[q.first.pos]
-			q.first.pos++
-			q.len--
+		q.mutex.Lock()
+		if v, ok = q.pop(); ok{
 			q.mutex.Unlock()
-			return
-		} else if q.first.pos == q.first.size{
-			for q.first.next == nil{
-				if q.closed{
-					q.mutex.Unlock()
-					return nil, true
-				}
-				q.mutex.Unlock()
-				//runtime.Gosched()
-				time.Sleep(time.Microsecond)
-				q.mutex.Lock()
-			}
-			first := q.first
-			q.first = first.next
-			first.free()
-			continue
+			return v, false
 		}
 		if q.closed{
 			q.mutex.Unlock()
 			return nil, true
 		}
 		q.mutex.Unlock()
-		//runtime.Gosched()
-		time.Sleep(time.Microsecond)
-		q.mutex.Lock()
+		<-q.notify
+	}
+}
+
+//批量取出最多 max 个元素, 队列为空时阻塞; latency>0 时最多再等待 latency 凑满一批
+func (q *queue)PopBatch(batch []interface{}, max int, latency time.Duration) ([]interface{}, bool) {
+	v, closed := q.PopBlock()
+	if closed{
+		return batch, true
+	}
+	batch = q.drain(append(batch, v), max)
+	if latency <= 0 || len(batch) >= max{
+		return batch, false
+	}
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	for len(batch) < max{
+		select {
+		case <-q.notify:
+			batch = q.drain(batch, max)
+			if q.Closed(){
+				return batch, false
+			}
+		case <-timer.C:
+			return batch, false
+		}
+	}
+	return batch, false
+}
+
+func (q *queue)drain(batch []interface{}, max int) []interface{} {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for len(batch) < max{
+		v, ok := q.pop()
+		if !ok{
+			break
+		}
+		batch = append(batch, v)
 	}
+	return batch
 }
 
 func (q *queue)Len() int {
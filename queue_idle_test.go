@@ -0,0 +1,41 @@
+//go:build unix
+
+package log
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func cpuTime(b *testing.B) time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		b.Fatal(err)
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+//每次空闲 10ms 后写入一条, 报告进程每次消耗的 CPU 时间
+func benchmarkQueueIdle(b *testing.B, popBlock func(q *queue) (interface{}, bool)) {
+	q := newQueue()
+	wg := startConsumer(q, popBlock)
+	start := cpuTime(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		time.Sleep(10 * time.Millisecond)
+		q.Push(i)
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(cpuTime(b)-start)/float64(b.N), "cpu-ns/op")
+	q.Close()
+	wg.Wait()
+}
+
+func BenchmarkQueueIdlePolling(b *testing.B) {
+	benchmarkQueueIdle(b, popBlockPolling)
+}
+
+func BenchmarkQueueIdleWakeup(b *testing.B) {
+	benchmarkQueueIdle(b, popBlockWakeup)
+}
@@ -0,0 +1,139 @@
+package log
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+//改为由 Push 唤醒之前的实现: 队列为空时每微秒轮询一次
+func popBlockPolling(q *queue) (v interface{}, closed bool) {
+	var ok bool
+	for {
+		q.mutex.Lock()
+		if v, ok = q.pop(); ok {
+			q.mutex.Unlock()
+			return v, false
+		}
+		if q.closed {
+			q.mutex.Unlock()
+			return nil, true
+		}
+		q.mutex.Unlock()
+		time.Sleep(time.Microsecond)
+	}
+}
+
+func popBlockWakeup(q *queue) (v interface{}, closed bool) {
+	return q.PopBlock()
+}
+
+//消费者取出 Push 的所有元素后返回
+func startConsumer(q *queue, popBlock func(q *queue) (interface{}, bool)) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			if _, closed := popBlock(q); closed {
+				return
+			}
+		}
+	}()
+	return &wg
+}
+
+func benchmarkQueueThroughput(b *testing.B, popBlock func(q *queue) (interface{}, bool)) {
+	q := newQueue()
+	wg := startConsumer(q, popBlock)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Push(i)
+	}
+	q.Close()
+	wg.Wait()
+}
+
+func BenchmarkQueueThroughputPolling(b *testing.B) {
+	benchmarkQueueThroughput(b, popBlockPolling)
+}
+
+func BenchmarkQueueThroughputWakeup(b *testing.B) {
+	benchmarkQueueThroughput(b, popBlockWakeup)
+}
+
+func TestQueuePopBlockWakeup(t *testing.T) {
+	q := newQueue()
+	done := make(chan interface{})
+	go func() {
+		v, _ := q.PopBlock()
+		done <- v
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Push(1)
+	select {
+	case v := <-done:
+		if v != 1 {
+			t.Fatalf("PopBlock = %v, want 1", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("PopBlock not woken by Push")
+	}
+	go func() {
+		_, closed := q.PopBlock()
+		done <- closed
+	}()
+	q.Close()
+	select {
+	case closed := <-done:
+		if closed != true {
+			t.Fatal("PopBlock on closed queue should return closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("PopBlock not woken by Close")
+	}
+}
+
+//队列已满时跳过标记移除最早的元素, 包括标记跨越节点边界的情况
+func TestQueuePushEvictKeep(t *testing.T) {
+	keep := func(v interface{}) bool { return v.(string)[0] == 'm' }
+	tests := []struct {
+		offset  int //先写入并取出的元素数, 使队列头部位于节点中的不同位置
+		items   []string
+		evicted interface{}
+		want    []string
+	}{
+		{0, []string{"a", "b", "c"}, "a", []string{"b", "c", "x"}},
+		{0, []string{"m1", "a", "b"}, "a", []string{"m1", "b", "x"}},
+		{0, []string{"m1", "m2", "a"}, "a", []string{"m1", "m2", "x"}},
+		{int(queueNodeSize) - 1, []string{"m1", "a", "b"}, "a", []string{"m1", "b", "x"}},
+		{int(queueNodeSize) - 1, []string{"m1", "m2", "a"}, "a", []string{"m1", "m2", "x"}},
+		{int(queueNodeSize) - 1, []string{"m1", "m2", "m3"}, nil, []string{"m1", "m2", "m3", "x"}},
+	}
+	for _, test := range tests {
+		q := newQueue()
+		for i := 0; i < test.offset; i++ {
+			q.Push("")
+			q.Pop()
+		}
+		q.SetCapacity(len(test.items))
+		for _, item := range test.items {
+			q.Push(item)
+		}
+		evicted, pushed := q.PushEvict("x", keep)
+		if !pushed || evicted != test.evicted {
+			t.Fatalf("%v: PushEvict = %v, %v; want %v", test.items, evicted, pushed, test.evicted)
+		}
+		if q.Len() != len(test.want) {
+			t.Fatalf("%v: Len = %d, want %d", test.items, q.Len(), len(test.want))
+		}
+		for _, want := range test.want {
+			if v, _ := q.Pop(); v != want {
+				t.Fatalf("%v: Pop = %v, want %v", test.items, v, want)
+			}
+		}
+		if v, _ := q.Pop(); v != nil {
+			t.Fatalf("%v: Pop = %v after all items", test.items, v)
+		}
+	}
+}
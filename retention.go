@@ -0,0 +1,308 @@
+package log
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//滚动文件保留策略, 各项为0表示不限制
+type retention struct {
+	maxAge   time.Duration //最长保留时间
+	maxFiles int           //最多保留的历史文件数(不含当前文件)
+	maxBytes int64         //所有文件(含当前文件)的最大总字节数
+}
+
+func (my *retention) enabled() bool {
+	return my.maxAge > 0 || my.maxFiles > 0 || my.maxBytes > 0
+}
+
+//各格式符输出内容对应的正则
+var verbPatterns = map[byte]string{
+	'Y': `\d{4}`, 'G': `\d{4}`,
+	'y': `\d{2}`, 'C': `\d{2}`, 'g': `\d{2}`, 'm': `\d{2}`, 'd': `\d{2}`, 'V': `\d{2}`,
+	'H': `\d{2}`, 'I': `\d{2}`, 'M': `\d{2}`, 'S': `\d{2}`,
+	'e': `[ \d]\d`, 'j': `\d{3}`, 'u': `\d`, 'w': `\d`,
+	'L': `\d{3}`, 'f': `\d{6}`, 'N': `\d{9}`, 's': `-?\d+`,
+	'a': `[A-Za-z]+`, 'A': `[A-Za-z]+`, 'b': `[A-Za-z]+`, 'h': `[A-Za-z]+`, 'B': `[A-Za-z]+`,
+	'p': `[AP]M`, 'z': `[+-]\d{4}`, 'Z': `[A-Za-z0-9+-]+`,
+	'n': `\n`, 't': `\t`,
+}
+
+//根据路径格式的文件名部分生成匹配滚动文件的正则, 目录部分的格式符不参与匹配
+func patternRegexp(filePathFormatter string, compressExt string) *regexp.Regexp {
+	_, name := filepath.Split(filePathFormatter)
+	var expr strings.Builder
+	expr.WriteByte('^')
+	writeFormatterPattern(&expr, name)
+	expr.WriteString(`(\.\d+)?`)
+	if compressExt != "" {
+		expr.WriteString("(" + regexp.QuoteMeta(compressExt) + ")?")
+	}
+	expr.WriteByte('$')
+	return regexp.MustCompile(expr.String())
+}
+
+func writeFormatterPattern(expr *strings.Builder, formatter string) {
+	length := len(formatter)
+	for i := 0; i < length; {
+		c := formatter[i]
+		if c == '%' {
+			if i+1 == length {
+				break
+			}
+			c2 := formatter[i+1]
+			if pattern, ok := verbPatterns[c2]; ok {
+				expr.WriteString(pattern)
+			} else if composite, ok := compositeVerbs[c2]; ok {
+				writeFormatterPattern(expr, composite)
+			} else {
+				expr.WriteString(regexp.QuoteMeta(formatter[i+1 : i+2]))
+			}
+			i += 2
+		} else {
+			expr.WriteString(regexp.QuoteMeta(formatter[i : i+1]))
+			i += 1
+		}
+	}
+}
+
+type rotatedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+//后台压缩及清理滚动文件
+type fileJanitor struct {
+	policy      retention
+	formatter   string
+	pattern     *regexp.Regexp
+	compressExt string
+	compressor  func(w io.Writer) (io.WriteCloser, error)
+	pending     []string //待压缩的文件
+	clock       Clock
+	onError     func(err error)
+	mu          sync.Mutex
+	active      string
+	signal      chan struct{}
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+func newFileJanitor(filePathFormatter string, onError func(err error)) *fileJanitor {
+	return &fileJanitor{formatter: filePathFormatter, pattern: patternRegexp(filePathFormatter, ""), clock: defaultClock, onError: onError}
+}
+
+func (my *fileJanitor) SetClock(clock Clock) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	if clock == nil {
+		clock = defaultClock
+	}
+	my.clock = clock
+}
+
+//当前文件切换后通知后台压缩上一个文件并清理
+func (my *fileJanitor) Notify(prevPath, active string) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.active = active
+	if prevPath != "" && my.compressor != nil {
+		my.pending = append(my.pending, prevPath)
+	}
+	my.trigger()
+}
+
+func (my *fileJanitor) SetCompressor(ext string, compressor func(w io.Writer) (io.WriteCloser, error)) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	if compressor == nil {
+		ext = ""
+	}
+	my.compressExt = ext
+	my.compressor = compressor
+	my.pattern = patternRegexp(my.formatter, ext)
+}
+
+func (my *fileJanitor) SetRetention(policy retention) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.policy = policy
+	my.trigger()
+}
+
+func (my *fileJanitor) trigger() {
+	if my.active == "" || (!my.policy.enabled() && len(my.pending) == 0) {
+		return
+	}
+	if my.signal == nil {
+		my.signal = make(chan struct{}, 1)
+		my.done = make(chan struct{})
+		my.wg.Add(1)
+		go my.run(my.signal, my.done)
+	}
+	select {
+	case my.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (my *fileJanitor) Close() {
+	my.mu.Lock()
+	done := my.done
+	my.signal = nil
+	my.done = nil
+	my.mu.Unlock()
+	if done != nil {
+		close(done)
+		my.wg.Wait()
+	}
+}
+
+func (my *fileJanitor) run(signal chan struct{}, done chan struct{}) {
+	defer my.wg.Done()
+	defer Exception(func(stack string, e error) {
+		my.onError(e)
+	})
+	for {
+		select {
+		case <-signal:
+			my.mu.Lock()
+			active, policy, pattern, clock := my.active, my.policy, my.pattern, my.clock
+			ext, compressor, pending := my.compressExt, my.compressor, my.pending
+			my.pending = nil
+			my.mu.Unlock()
+			for _, path := range pending {
+				if path != active && compressor != nil {
+					my.compress(path, ext, compressor)
+				}
+			}
+			my.clean(active, policy, pattern, clock.Now())
+		case <-done:
+			//退出前压缩已切换的文件, 否则不会再被压缩
+			my.mu.Lock()
+			active, ext, compressor, pending := my.active, my.compressExt, my.compressor, my.pending
+			my.pending = nil
+			my.mu.Unlock()
+			for _, path := range pending {
+				if path != active && compressor != nil {
+					my.compress(path, ext, compressor)
+				}
+			}
+			return
+		}
+	}
+}
+
+//压缩到临时文件后重命名为 path+ext, 再删除原文件
+func (my *fileJanitor) compress(path string, ext string, compressor func(w io.Writer) (io.WriteCloser, error)) {
+	src, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			my.onError(err)
+		}
+		return
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		my.onError(err)
+		return
+	}
+	if has, _ := exist(path + ext); has {
+		my.onError(newError("compress %s error: %s already exists", path, path+ext))
+		return
+	}
+	tmpPath := path + ext + ".tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		my.onError(err)
+		return
+	}
+	err = compressTo(dst, src, compressor)
+	if e := dst.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path+ext)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		my.onError(newError("compress %s error: %v", path, err))
+		return
+	}
+	//保持修改时间, 以便按时间清理
+	os.Chtimes(path+ext, info.ModTime(), info.ModTime())
+	if err = os.Remove(path); err != nil {
+		my.onError(err)
+	}
+}
+
+func compressTo(dst *os.File, src io.Reader, compressor func(w io.Writer) (io.WriteCloser, error)) error {
+	writer, err := compressor(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(writer, src); err != nil {
+		writer.Close()
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return dst.Sync()
+}
+
+func (my *fileJanitor) clean(active string, policy retention, pattern *regexp.Regexp, now time.Time) {
+	if active == "" || !policy.enabled() {
+		return
+	}
+	folderPath := filepath.Dir(active)
+	entries, err := os.ReadDir(folderPath)
+	if err != nil {
+		my.onError(err)
+		return
+	}
+	var total int64
+	var files []rotatedFile
+	for _, entry := range entries {
+		if entry.IsDir() || !pattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if !os.IsNotExist(err) {
+				my.onError(err)
+			}
+			continue
+		}
+		path := filepath.Join(folderPath, entry.Name())
+		if path == active {
+			total += info.Size()
+			continue
+		}
+		files = append(files, rotatedFile{path: path, size: info.Size(), modTime: info.ModTime()})
+	}
+	//从新到旧, 超出限制的都删除
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	kept := 0
+	for _, file := range files {
+		expired := policy.maxAge > 0 && now.Sub(file.modTime) > policy.maxAge
+		tooMany := policy.maxFiles > 0 && kept >= policy.maxFiles
+		tooLarge := policy.maxBytes > 0 && total+file.size > policy.maxBytes
+		if expired || tooMany || tooLarge {
+			if err = os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+				my.onError(err)
+			}
+			continue
+		}
+		kept++
+		total += file.size
+	}
+}
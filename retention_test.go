@@ -0,0 +1,40 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+//Wait 退出时待压缩的文件都应压缩完成
+func TestCompressPendingOnClose(t *testing.T) {
+	dir := t.TempDir()
+	writer := NewLogFileWriter(filepath.Join(dir, "x.log"))
+	writer.SetMaxSize(200)
+	writer.SetCompress(LOG_COMPRESS_GZIP)
+	logger := NewLogger(LOG_LEVEL_DEBUG, true, writer)
+	for i := 0; i < 20; i++ {
+		logger.InfoF("line %d of the compression test", i)
+	}
+	logger.Wait()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plain []string
+	compressed := 0
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".gz") {
+			compressed++
+		} else {
+			plain = append(plain, entry.Name())
+		}
+	}
+	sort.Strings(plain)
+	if len(plain) != 1 || compressed == 0 {
+		t.Fatalf("uncompressed files %v, compressed %d; want only the active file left uncompressed", plain, compressed)
+	}
+}
@@ -0,0 +1,99 @@
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+//slog.Handler 实现, 日志经由 stdLogger 的等级过滤、异步队列及 writer 输出
+//分组以 "group.key" 形式展开为字段
+type SlogHandler struct {
+	logger *stdLogger
+	prefix string  //WithGroup 设置的分组前缀 "a.b."
+	fields []Field //WithAttrs 设置的字段
+}
+
+//logger 为 nil 时使用默认 logger, 如 slog.SetDefault(slog.New(log.NewSlogHandler(nil)))
+func NewSlogHandler(logger *stdLogger) *SlogHandler {
+	if logger == nil {
+		logger = &log
+	}
+	return &SlogHandler{logger: logger}
+}
+
+//slog 等级: Debug=-4, Info=0, Warn=4, Error=8
+func slogLevel(level slog.Level) int {
+	switch {
+	case level < slog.LevelDebug:
+		return LOG_LEVEL_TRACE
+	case level < slog.LevelInfo:
+		return LOG_LEVEL_DEBUG
+	case level < slog.LevelWarn:
+		return LOG_LEVEL_INFO
+	case level < slog.LevelError:
+		return LOG_LEVEL_WARN
+	case level < slog.LevelError+4:
+		return LOG_LEVEL_ERROR
+	default:
+		return LOG_LEVEL_FATAL
+	}
+}
+
+func (my *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return my.logger.Enabled(slogLevel(level))
+}
+
+//调用位置取自 record.PC, 时间取自 record.Time, 并附带从 ctx 中提取的字段
+func (my *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	fields := make([]Field, len(my.fields), len(my.fields)+record.NumAttrs())
+	copy(fields, my.fields)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendSlogAttr(fields, my.prefix, attr)
+		return true
+	})
+	if len(fields) == 0 {
+		fields = nil
+	}
+	fields = withContextFields(ctx, fields)
+	my.logger.outputAt(slogLevel(record.Level), record.PC, record.Time, record.Message, fields)
+	return nil
+}
+
+func (my *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return my
+	}
+	fields := make([]Field, len(my.fields), len(my.fields)+len(attrs))
+	copy(fields, my.fields)
+	for _, attr := range attrs {
+		fields = appendSlogAttr(fields, my.prefix, attr)
+	}
+	return &SlogHandler{logger: my.logger, prefix: my.prefix, fields: fields}
+}
+
+func (my *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return my
+	}
+	return &SlogHandler{logger: my.logger, prefix: my.prefix + name + ".", fields: my.fields}
+}
+
+//按 slog 规则忽略空属性, 分组展开为带前缀的字段, 空名称的分组直接展开
+func appendSlogAttr(fields []Field, prefix string, attr slog.Attr) []Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			fields = appendSlogAttr(fields, prefix, member)
+		}
+		return fields
+	}
+	return append(fields, Field{Key: prefix + attr.Key, Value: attr.Value.Any()})
+}
@@ -0,0 +1,116 @@
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+//经由 slog.New(NewSlogHandler(logger)) 输出到 JSON writer
+func TestSlogHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		log    func(l *slog.Logger) int //返回调用所在行号
+		level  string
+		fields map[string]interface{}
+	}{
+		{"trace", func(l *slog.Logger) int {
+			_, _, line, _ := runtime.Caller(0)
+			l.Log(context.Background(), slog.LevelDebug-4, "m")
+			return line + 1
+		}, "TRACE", nil},
+		{"debug", func(l *slog.Logger) int {
+			_, _, line, _ := runtime.Caller(0)
+			l.Debug("m")
+			return line + 1
+		}, "DEBUG", nil},
+		{"info attrs", func(l *slog.Logger) int {
+			_, _, line, _ := runtime.Caller(0)
+			l.Info("m", "user", 42, slog.String("ip", "::1"))
+			return line + 1
+		}, "INFO", map[string]interface{}{"user": 42.0, "ip": "::1"}},
+		{"warn", func(l *slog.Logger) int {
+			_, _, line, _ := runtime.Caller(0)
+			l.Warn("m")
+			return line + 1
+		}, "WARN", nil},
+		{"error", func(l *slog.Logger) int {
+			_, _, line, _ := runtime.Caller(0)
+			l.Error("m")
+			return line + 1
+		}, "ERROR", nil},
+		{"fatal", func(l *slog.Logger) int {
+			_, _, line, _ := runtime.Caller(0)
+			l.Log(context.Background(), slog.LevelError+4, "m")
+			return line + 1
+		}, "FATAL", nil},
+		{"group attr", func(l *slog.Logger) int {
+			_, _, line, _ := runtime.Caller(0)
+			l.Info("m", slog.Group("req", "method", "GET", slog.Group("url", "path", "/")))
+			return line + 1
+		}, "INFO", map[string]interface{}{"req.method": "GET", "req.url.path": "/"}},
+		{"empty groups and attrs dropped", func(l *slog.Logger) int {
+			_, _, line, _ := runtime.Caller(0)
+			l.Info("m", slog.Group("empty"), slog.Attr{}, slog.Group("", "inline", 1))
+			return line + 1
+		}, "INFO", map[string]interface{}{"inline": 1.0}},
+		{"WithAttrs WithGroup", func(l *slog.Logger) int {
+			child := l.With("app", "billing").WithGroup("req").With("id", 7).WithGroup("").WithGroup("db")
+			_, _, line, _ := runtime.Caller(0)
+			child.Info("m", "rows", 3)
+			return line + 1
+		}, "INFO", map[string]interface{}{"app": "billing", "req.id": 7.0, "req.db.rows": 3.0}},
+		{"WithGroup without attrs", func(l *slog.Logger) int {
+			_, _, line, _ := runtime.Caller(0)
+			l.WithGroup("unused").Info("m")
+			return line + 1
+		}, "INFO", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &syncBuffer{}
+			writer := NewLogStdWriter(out)
+			writer.SetFormat(LOG_FORMAT_JSON)
+			line := test.log(slog.New(NewSlogHandler(NewLogger(LOG_LEVEL_TRACE, false, writer))))
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(out.String()), &entry); err != nil {
+				t.Fatalf("%v: %q", err, out.String())
+			}
+			if entry["level"] != test.level || entry["msg"] != "m" {
+				t.Fatalf("level, msg = %v, %v; want %s, m", entry["level"], entry["msg"], test.level)
+			}
+			if want := fmt.Sprintf("slog_test.go:%d", line); entry["caller"] != want {
+				t.Fatalf("caller = %v, want %s", entry["caller"], want)
+			}
+			for _, key := range []string{"level", "time", "caller", "msg"} {
+				delete(entry, key)
+			}
+			if len(entry) != len(test.fields) {
+				t.Fatalf("fields = %v, want %v", entry, test.fields)
+			}
+			for key, value := range test.fields {
+				if entry[key] != value {
+					t.Fatalf("fields = %v, want %v", entry, test.fields)
+				}
+			}
+		})
+	}
+}
+
+//低于 logger 等级的日志不输出
+func TestSlogHandlerEnabled(t *testing.T) {
+	out := &syncBuffer{}
+	l := slog.New(NewSlogHandler(NewLogger(LOG_LEVEL_WARN, false, NewLogStdWriter(out))))
+	l.Info("info")
+	l.Warn("warn")
+	if got := out.String(); strings.Contains(got, "info") || !strings.Contains(got, "warn") {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+	"runtime"
+	"sync"
+	"time"
+)
+
+//本模块的包路径
+var selfPackage, _ = splitFunction(runtime.FuncForPC(callerPC(0)).Name())
+
+//查找调用位置时跳过的包, 写入经由这些包转发
+var forwardPackages = map[string]bool{"log": true, "fmt": true, "io": true, "bufio": true}
+
+//跳过本模块及 forwardPackages 中的调用, 返回实际输出日志的调用位置
+func externalCallerPC(skip int) uintptr {
+	var pcs [32]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	for i := 0; i < n; i++ {
+		pkg := frameOf(pcs[i]).pkg
+		if pkg != selfPackage && !forwardPackages[pkg] {
+			return pcs[i]
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return pcs[n-1]
+}
+
+//按行输出到 logger 的 io.Writer, 每行一条日志; 不完整的行缓存到下次写入或 Flush
+type LineWriter struct {
+	logger *stdLogger
+	level  int
+	buf    []byte
+	mu     sync.Mutex
+}
+
+//logger 为 nil 时使用默认 logger
+func NewLineWriter(logger *stdLogger, level int) *LineWriter {
+	if logger == nil {
+		logger = &log
+	}
+	return &LineWriter{logger: logger, level: level}
+}
+
+func (my *LineWriter) Write(p []byte) (int, error) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	n := len(p)
+	var pc uintptr
+	if bytes.IndexByte(p, '\n') != -1 {
+		pc = externalCallerPC(1)
+	}
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i == -1 {
+			my.buf = append(my.buf, p...)
+			break
+		}
+		line := p[:i]
+		if len(my.buf) > 0 {
+			my.buf = append(my.buf, line...)
+			line = my.buf
+		}
+		my.output(pc, line)
+		my.buf = my.buf[:0]
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+//输出缓存中不完整的行
+func (my *LineWriter) Flush() {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	if len(my.buf) > 0 {
+		my.output(externalCallerPC(1), my.buf)
+		my.buf = my.buf[:0]
+	}
+}
+
+func (my *LineWriter) output(pc uintptr, line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) == 0 || !my.logger.Enabled(my.level) {
+		return
+	}
+	my.logger.outputAt(my.level, pc, time.Time{}, string(line), nil)
+}
+
+//将标准库 log 的默认输出重定向到 logger, 使用 logger 的头部格式及调用位置; 返回恢复原设置的函数
+//logger 为 nil 时使用默认 logger
+func RedirectStdLog(logger *stdLogger, level int) (restore func()) {
+	writer, flags := stdlog.Writer(), stdlog.Flags()
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(NewLineWriter(logger, level))
+	return func() {
+		stdlog.SetOutput(writer)
+		stdlog.SetFlags(flags)
+	}
+}
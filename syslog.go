@@ -0,0 +1,390 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+//syslog 协议格式
+const (
+	LOG_SYSLOG_RFC5424 = iota //<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+	LOG_SYSLOG_RFC3164        //<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+)
+
+//syslog facility
+const (
+	LOG_FACILITY_KERN     = 0
+	LOG_FACILITY_USER     = 1
+	LOG_FACILITY_MAIL     = 2
+	LOG_FACILITY_DAEMON   = 3
+	LOG_FACILITY_AUTH     = 4
+	LOG_FACILITY_SYSLOG   = 5
+	LOG_FACILITY_LPR      = 6
+	LOG_FACILITY_NEWS     = 7
+	LOG_FACILITY_UUCP     = 8
+	LOG_FACILITY_CRON     = 9
+	LOG_FACILITY_AUTHPRIV = 10
+	LOG_FACILITY_FTP      = 11
+	LOG_FACILITY_LOCAL0   = 16
+	LOG_FACILITY_LOCAL1   = 17
+	LOG_FACILITY_LOCAL2   = 18
+	LOG_FACILITY_LOCAL3   = 19
+	LOG_FACILITY_LOCAL4   = 20
+	LOG_FACILITY_LOCAL5   = 21
+	LOG_FACILITY_LOCAL6   = 22
+	LOG_FACILITY_LOCAL7   = 23
+)
+
+const syslogTimeout = 5 * time.Second
+const syslogRetryInterval = time.Second //连接失败后重试的最短间隔
+
+//本地 syslog 的 unix socket 路径
+var syslogLocalPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+//等级对应的 syslog severity: 2 crit, 3 err, 4 warning, 5 notice, 6 info, 7 debug
+func syslogSeverity(level int) int {
+	switch {
+	case level >= LOG_LEVEL_FATAL:
+		return 2
+	case level >= LOG_LEVEL_ERROR:
+		return 3
+	case level >= LOG_LEVEL_WARN:
+		return 4
+	case level > LOG_LEVEL_INFO:
+		return 5
+	case level == LOG_LEVEL_INFO:
+		return 6
+	default:
+		return 7
+	}
+}
+
+//输出到 syslog 服务的 writer; 头部由协议决定, SetFormatHeader 不生效,
+//消息部分按 SetFormat 及 SetLayout 输出, 默认为 "file:line 内容"
+type logSyslogWriter struct {
+	logBaseWriter
+	network   string
+	addr      string
+	protocol  int
+	facility  int
+	appName   string
+	sdID      string //RFC 5424 结构化数据的 SD-ID
+	conn      net.Conn
+	eof       chan struct{} //流式连接被对端关闭时关闭
+	stream    bool          //tcp 使用 octet counting 分帧, unix 流使用换行分帧
+	local     bool          //本地 unix socket, RFC 3164 不输出主机名
+	lastRetry time.Time
+	dialErr   error //最近一次连接失败的错误, 重试间隔内直接返回
+	mu        sync.Mutex
+}
+
+//network 为 "udp", "tcp" 或 "unix"/"unixgram"; network 及 addr 均为空时连接本地 /dev/log
+func NewLogSyslogWriter(network string, addr string) *logSyslogWriter {
+	out := &logSyslogWriter{network: network, addr: addr, facility: LOG_FACILITY_USER, sdID: "fields@32473"}
+	out.appName = syslogName(filepath.Base(os.Args[0]), 48)
+	return out
+}
+
+//LOG_SYSLOG_RFC5424 或 LOG_SYSLOG_RFC3164
+func (my *logSyslogWriter) SetProtocol(protocol int) {
+	if protocol < LOG_SYSLOG_RFC5424 || protocol > LOG_SYSLOG_RFC3164 {
+		return
+	}
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.protocol = protocol
+}
+
+//默认 LOG_FACILITY_USER
+func (my *logSyslogWriter) SetFacility(facility int) {
+	if facility < LOG_FACILITY_KERN || facility > LOG_FACILITY_LOCAL7 {
+		return
+	}
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.facility = facility
+}
+
+//默认为程序名, 最长48个字符
+func (my *logSyslogWriter) SetAppName(appName string) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.appName = syslogName(appName, 48)
+}
+
+//设置 RFC 5424 结构化数据的 SD-ID, 默认 fields@32473; 字段作为该元素的参数输出
+func (my *logSyslogWriter) SetStructuredDataID(id string) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	my.sdID = syslogName(id, 32)
+}
+
+func (my *logSyslogWriter) Write(item *Entry) {
+	if !my.accept(item) {
+		return
+	}
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	buf := getBuffer(itemSize(item) + 64)
+	defer buf.free()
+	my.time.flushTo(item.unix, item.nsec)
+	my.frame(buf, item)
+	my.send(*buf)
+}
+
+//流式连接时合并为一次写入, 数据报连接每条单独发送
+func (my *logSyslogWriter) WriteBatch(items []*Entry) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	buf := getBuffer(itemsSize(items) + 64*len(items))
+	defer buf.free()
+	for _, item := range items {
+		if !my.accept(item) {
+			continue
+		}
+		my.time.flushTo(item.unix, item.nsec)
+		if !my.stream {
+			*buf = (*buf)[:0]
+		}
+		my.frame(buf, item)
+		if !my.stream {
+			my.send(*buf)
+		}
+	}
+	if my.stream && len(*buf) > 0 {
+		my.send(*buf)
+	}
+}
+
+func (my *logSyslogWriter) Close() {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	if my.conn != nil {
+		my.conn.Close()
+		my.conn = nil
+	}
+}
+
+func (my *logSyslogWriter) connect() error {
+	if my.conn != nil {
+		return nil
+	}
+	if my.dialErr != nil && time.Since(my.lastRetry) < syslogRetryInterval {
+		return my.dialErr
+	}
+	var conn net.Conn
+	var err error
+	if my.network == "" && my.addr == "" {
+		conn, err = dialSyslogLocal()
+	} else {
+		conn, err = net.DialTimeout(my.network, my.addr, syslogTimeout)
+	}
+	if err != nil {
+		my.lastRetry, my.dialErr = time.Now(), err
+		return err
+	}
+	my.dialErr = nil
+	my.conn = conn
+	switch conn.(type) {
+	case *net.TCPConn:
+		my.stream, my.local = true, false
+	case *net.UnixConn:
+		my.stream, my.local = conn.LocalAddr().Network() == "unix", true
+	default:
+		my.stream, my.local = false, false
+	}
+	my.eof = nil
+	if my.stream {
+		//对端关闭后第一次写入仍会成功, 由后台读取发现连接关闭; syslog 服务不会发送数据
+		my.eof = make(chan struct{})
+		go func(conn net.Conn, eof chan struct{}) {
+			io.Copy(io.Discard, conn)
+			close(eof)
+		}(conn, my.eof)
+	}
+	return nil
+}
+
+func (my *logSyslogWriter) peerClosed() bool {
+	if my.eof == nil {
+		return false
+	}
+	select {
+	case <-my.eof:
+		return true
+	default:
+		return false
+	}
+}
+
+func dialSyslogLocal() (net.Conn, error) {
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogLocalPaths {
+			if conn, err := net.DialTimeout(network, path, syslogTimeout); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, newError("syslog: local syslog server not found")
+}
+
+//写入失败时重新连接并重试一次
+func (my *logSyslogWriter) send(data []byte) {
+	var err error
+	for i := 0; i < 2; i++ {
+		if err = my.connect(); err != nil {
+			break
+		}
+		if my.peerClosed() {
+			my.conn.Close()
+			my.conn = nil
+			continue
+		}
+		my.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		if _, err = my.conn.Write(data); err == nil {
+			return
+		}
+		my.conn.Close()
+		my.conn = nil
+	}
+	my.reportError(newError("logSyslogWriter write error: %v", err))
+}
+
+//按连接类型分帧: tcp 为 "长度 消息", unix 流为 "消息\n", 数据报不分帧
+//连接类型在连接后确定, 先尝试连接, 失败时由 send 报告错误
+func (my *logSyslogWriter) frame(buf *Buffer, item *Entry) {
+	my.connect()
+	if !my.stream || my.local {
+		my.encode(buf, item)
+		if my.stream {
+			buf.AppendBytes('\n')
+		}
+		return
+	}
+	msg := getBuffer(itemSize(item) + 64)
+	defer msg.free()
+	my.encode(msg, item)
+	buf.AppendInt(len(*msg), 0)
+	buf.AppendBytes(' ')
+	buf.AppendBytes(*msg...)
+}
+
+func (my *logSyslogWriter) encode(buf *Buffer, item *Entry) {
+	buf.AppendBytes('<')
+	buf.AppendInt(my.facility<<3|syslogSeverity(item.level), 0)
+	buf.AppendBytes('>')
+	if my.protocol == LOG_SYSLOG_RFC3164 {
+		my.time.appendFormat(buf, "%b %e %H:%M:%S")
+		buf.AppendBytes(' ')
+		if !my.local {
+			buf.AppendString(hostname)
+			buf.AppendBytes(' ')
+		}
+		buf.AppendString(my.appName)
+		buf.AppendBytes('[')
+		buf.AppendInt(pid, 0)
+		buf.AppendString("]: ")
+		my.appendMessage(buf, item, true)
+		return
+	}
+	buf.AppendString("1 ")
+	appendISOTime(buf, &my.time)
+	buf.AppendBytes(' ')
+	buf.AppendString(syslogName(hostname, 255))
+	buf.AppendBytes(' ')
+	buf.AppendString(my.appName)
+	buf.AppendBytes(' ')
+	buf.AppendInt(pid, 0)
+	buf.AppendString(" - ")
+	appendStructuredData(buf, my.sdID, item.fields)
+	buf.AppendBytes(' ')
+	my.appendMessage(buf, item, false)
+}
+
+//RFC 5424 中字段放在结构化数据中, 消息部分不再输出
+func (my *logSyslogWriter) appendMessage(buf *Buffer, item *Entry, withFields bool) {
+	if my.format == LOG_FORMAT_JSON {
+		appendJSONEntry(buf, &my.time, item, my.callerMode)
+		return
+	}
+	if my.layout != nil {
+		my.layout.render(buf, &my.time, item, my.callerMode, levelOf(item.level).Label)
+		return
+	}
+	buf.AppendString(item.frame.File(my.callerMode))
+	buf.AppendBytes(':')
+	buf.AppendInt(item.frame.line, 0)
+	buf.AppendBytes(' ')
+	if item.name != "" {
+		buf.AppendString(item.name)
+		buf.AppendString(": ")
+	}
+	buf.AppendString(item.content)
+	if withFields {
+		appendTextFields(buf, item.fields)
+	}
+}
+
+//[fields@32473 key="value" ...], 没有字段时为 -
+func appendStructuredData(buf *Buffer, sdID string, fields []Field) {
+	if len(fields) == 0 || sdID == "-" {
+		buf.AppendBytes('-')
+		return
+	}
+	buf.AppendBytes('[')
+	buf.AppendString(sdID)
+	for _, field := range fields {
+		buf.AppendBytes(' ')
+		buf.AppendString(syslogName(field.Key, 32))
+		buf.AppendString(`="`)
+		appendSDValue(buf, field.Value)
+		buf.AppendBytes('"')
+	}
+	buf.AppendBytes(']')
+}
+
+//参数值中的 " \ ] 需要转义
+func appendSDValue(buf *Buffer, value interface{}) {
+	var s string
+	switch x := value.(type) {
+	case string:
+		s = x
+	case error:
+		s = x.Error()
+	case fmt.Stringer:
+		s = x.String()
+	default:
+		tmp := getBuffer(32)
+		defer tmp.free()
+		appendTextValue(tmp, value)
+		s = string(*tmp)
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '"' || c == '\\' || c == ']' {
+			buf.AppendBytes('\\')
+		}
+		buf.AppendBytes(s[i])
+	}
+}
+
+//RFC 5424 的名称只能包含可见 ASCII 字符且不含 = ] " 空格, 其它字符替换为 _; 空名称为 -
+func syslogName(s string, maxLen int) string {
+	if s == "" {
+		return "-"
+	}
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r >= 0x7f || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+}
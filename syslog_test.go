@@ -0,0 +1,111 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type syslogMessage struct {
+	conn int //第几个连接收到, 从 1 开始
+	msg  string
+}
+
+//按 octet counting 分帧读取 "长度 消息", conns 返回服务端连接以便主动断开
+func listenSyslogTCP(t *testing.T) (addr string, messages chan syslogMessage, conns chan net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	messages = make(chan syslogMessage, 16)
+	conns = make(chan net.Conn, 4)
+	go func() {
+		for index := 1; ; index++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+			go func(conn net.Conn, index int) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					var n int
+					if _, err := fmt.Fscanf(r, "%d ", &n); err != nil {
+						return
+					}
+					b := make([]byte, n)
+					if _, err := io.ReadFull(r, b); err != nil {
+						return
+					}
+					messages <- syslogMessage{conn: index, msg: string(b)}
+				}
+			}(conn, index)
+		}
+	}()
+	return ln.Addr().String(), messages, conns
+}
+
+func receiveSyslog(t *testing.T, messages chan syslogMessage) syslogMessage {
+	t.Helper()
+	select {
+	case m := <-messages:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("syslog message not received")
+		return syslogMessage{}
+	}
+}
+
+//tcp 使用 octet counting 分帧, 对端关闭连接后重新连接并发送后续日志
+func TestSyslogTCPReconnect(t *testing.T) {
+	addr, messages, conns := listenSyslogTCP(t)
+	writer := NewLogSyslogWriter("tcp", addr)
+	writer.SetAppName("app")
+	var errs []error
+	writer.SetErrorHandler(func(err error) { errs = append(errs, err) })
+	logger := NewLogger(LOG_LEVEL_DEBUG, false, writer)
+	defer logger.Wait()
+
+	logger.InfoW("first\nline", "user", `a"b]c`)
+	logger.Error("second")
+	first, second := receiveSyslog(t, messages), receiveSyslog(t, messages)
+	if first.conn != 1 || !strings.HasPrefix(first.msg, "<14>1 ") || !strings.HasSuffix(first.msg, " first\nline") {
+		t.Fatalf("first message = %+v", first)
+	}
+	if !strings.Contains(first.msg, ` app `) || !strings.Contains(first.msg, ` [fields@32473 user="a\"b\]c"] `) {
+		t.Fatalf("first message header = %q", first.msg)
+	}
+	if second.conn != 1 || !strings.HasPrefix(second.msg, "<11>1 ") || !strings.HasSuffix(second.msg, " second") {
+		t.Fatalf("second message = %+v", second)
+	}
+
+	(<-conns).Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		writer.mu.Lock()
+		closed := writer.peerClosed()
+		writer.mu.Unlock()
+		if closed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("peer close not detected")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	logger.Warn("third")
+	if third := receiveSyslog(t, messages); third.conn != 2 || !strings.HasPrefix(third.msg, "<12>1 ") || !strings.HasSuffix(third.msg, " third") {
+		t.Fatalf("third message = %+v", third)
+	}
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const vmoduleNoRule = -1
+
+//按包或文件设置等级的规则
+type vmoduleRule struct {
+	pattern  string
+	segments int  //pattern 的路径段数, 与目标路径末尾相同段数比较
+	file     bool //以 .go 结尾时匹配文件路径, 否则匹配包路径
+	level    int
+}
+
+//与 glog 相同, 包规则同时与 "包路径/去掉 .go 的文件名" 比较:
+//db/* 匹配包 db 中的文件及 db 的直接子包, db 只匹配包 db
+func (my *vmoduleRule) match(frame *callerFrame) bool {
+	if my.file {
+		return my.matchPath(filepath.ToSlash(frame.path))
+	}
+	return my.matchPath(frame.pkg) || my.matchPath(frame.pkg+"/"+strings.TrimSuffix(frame.file, ".go"))
+}
+
+func (my *vmoduleRule) matchPath(target string) bool {
+	matched, _ := path.Match(my.pattern, lastSegments(target, my.segments))
+	return matched
+}
+
+//github.com/user/project/db/mysql, 2 => db/mysql
+func lastSegments(s string, n int) string {
+	i := len(s)
+	for ; n > 0 && i > 0; n-- {
+		i = strings.LastIndexByte(s[:i], '/')
+		if i == -1 {
+			return s
+		}
+	}
+	return s[i+1:]
+}
+
+//一组规则及按调用位置缓存的匹配结果, 规则修改时整体替换
+type vmodule struct {
+	rules    []vmoduleRule
+	minLevel int      //规则中的最低等级
+	cache    sync.Map //pc => 匹配规则的等级或 vmoduleNoRule
+}
+
+//"db/*=debug, http/handler.go=trace, *=info"
+func parseVModule(spec string) (*vmodule, error) {
+	out := &vmodule{minLevel: LOG_LEVEL_OFF}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		eq := strings.LastIndexByte(item, '=')
+		if eq <= 0 {
+			return nil, newError("vmodule: invalid rule %q, want pattern=level", item)
+		}
+		pattern := strings.TrimSpace(item[:eq])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, newError("vmodule: invalid pattern %q: %v", pattern, err)
+		}
+		level, err := ParseLevel(item[eq+1:])
+		if err != nil {
+			return nil, newError("vmodule: rule %q: %v", item, err)
+		}
+		rule := vmoduleRule{pattern: pattern, segments: strings.Count(pattern, "/") + 1, file: strings.HasSuffix(pattern, ".go"), level: level}
+		out.rules = append(out.rules, rule)
+		if level < out.minLevel {
+			out.minLevel = level
+		}
+	}
+	if len(out.rules) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+//调用位置对应的规则等级, 第一个匹配的规则生效
+func (my *vmodule) levelAt(pc uintptr) int {
+	if level, ok := my.cache.Load(pc); ok {
+		return level.(int)
+	}
+	level := vmoduleNoRule
+	frame := frameOf(pc)
+	for i := range my.rules {
+		if my.rules[i].match(frame) {
+			level = my.rules[i].level
+			break
+		}
+	}
+	my.cache.Store(pc, level)
+	return level
+}
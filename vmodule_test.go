@@ -0,0 +1,81 @@
+package log
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestLastSegments(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"github.com/user/project/db/mysql", 1, "mysql"},
+		{"github.com/user/project/db/mysql", 2, "db/mysql"},
+		{"github.com/user/project/db/mysql", 9, "github.com/user/project/db/mysql"},
+		{"main", 1, "main"},
+		{"main", 2, "main"},
+	}
+	for _, test := range tests {
+		if got := lastSegments(test.s, test.n); got != test.want {
+			t.Errorf("lastSegments(%q, %d) = %q, want %q", test.s, test.n, got, test.want)
+		}
+	}
+}
+
+//包规则与 glog 相同, 同时匹配包路径及 "包路径/文件名"
+func TestVModuleMatch(t *testing.T) {
+	frame := func(pkg, file string) *callerFrame {
+		return &callerFrame{path: "/src/" + pkg + "/" + file, file: file, pkg: pkg}
+	}
+	db := frame("github.com/user/project/db", "conn.go")
+	mysql := frame("github.com/user/project/db/mysql", "driver.go")
+	deep := frame("github.com/user/project/db/mysql/pool", "pool.go")
+	api := frame("github.com/user/project/api", "handler.go")
+	tests := []struct {
+		pattern string
+		frame   *callerFrame
+		want    bool
+	}{
+		{"db/*", db, true},
+		{"db/*", mysql, true},
+		{"db/*", deep, false},
+		{"db/*", api, false},
+		{"db/conn", db, true},
+		{"db/c*", db, true},
+		{"db/driver", db, false},
+		{"db", db, true},
+		{"db", mysql, false},
+		{"mysql", mysql, true},
+		{"db/mysql/*", deep, true},
+		{"*", api, true},
+		{"api/handler.go", api, true},
+		{"db/handler.go", api, false},
+		{"handler.go", api, true},
+	}
+	for _, test := range tests {
+		vm, err := parseVModule(test.pattern + "=debug")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := vm.rules[0].match(test.frame); got != test.want {
+			t.Errorf("%q match %s/%s = %v, want %v", test.pattern, test.frame.pkg, test.frame.file, got, test.want)
+		}
+	}
+}
+
+func TestVModuleLevelAt(t *testing.T) {
+	pc, _, _, _ := runtime.Caller(0)
+	vm, err := parseVModule("other/*=error, log/vmodule_test=trace, *=info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level := vm.levelAt(pc); level != LOG_LEVEL_TRACE {
+		t.Fatalf("levelAt = %d, want LOG_LEVEL_TRACE", level)
+	}
+	vm, _ = parseVModule("other/*=error")
+	if level := vm.levelAt(pc); level != vmoduleNoRule {
+		t.Fatalf("levelAt = %d, want vmoduleNoRule", level)
+	}
+}
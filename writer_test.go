@@ -0,0 +1,50 @@
+package log_test
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jingyanbin/log"
+)
+
+//包外只实现 Write 及 Close 的 writer
+type recordWriter struct {
+	lines []string
+	mu    sync.Mutex
+}
+
+func (my *recordWriter) Write(entry *log.Entry) {
+	my.mu.Lock()
+	defer my.mu.Unlock()
+	line := fmt.Sprintf("%d %s %s:%d %s %s", entry.Level(), entry.Name(), entry.File()[strings.LastIndexByte(entry.File(), '/')+1:], entry.Line(), entry.Message(), entry.Time().UTC().Format(time.RFC3339))
+	for _, field := range entry.Fields() {
+		line += fmt.Sprintf(" %s=%v", field.Key, field.Value)
+	}
+	my.lines = append(my.lines, line)
+}
+
+func (my *recordWriter) Close() {}
+
+func TestCustomWriter(t *testing.T) {
+	writer := &recordWriter{}
+	logger := log.NewLogger(log.LOG_LEVEL_DEBUG, false, writer)
+	clock := log.NewManualClock(time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC))
+	logger.SetClock(clock)
+	//未实现的设置方法被跳过
+	logger.SetFormat(log.LOG_FORMAT_JSON)
+	logger.SetColor(true)
+	if err := logger.SetLayout("%m"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, line, _ := runtime.Caller(0)
+	logger.Named("db").InfoW("query", "rows", 3)
+	logger.Wait()
+	want := fmt.Sprintf("%d db writer_test.go:%d query 2024-05-06T07:08:09Z rows=3", log.LOG_LEVEL_INFO, line+1)
+	if len(writer.lines) != 1 || writer.lines[0] != want {
+		t.Fatalf("lines = %q, want %q", writer.lines, want)
+	}
+}